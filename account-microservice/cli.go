@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"account-microservice/notifier"
+	"account-microservice/services"
+	"account-microservice/utils"
+)
+
+const usage = `Usage:
+  main                         start the HTTP server
+  main rotate-keys [use...]    rotate signing keys (access, refresh; default both)
+  main import-accounts [-dry-run] [-credentials invite|password] FILE
+                               create accounts from a .csv or .json file
+  main migrate-legacy-doctors  move the legacy doctors table to doctor accounts`
+
+func runCommand(args []string) {
+	switch args[0] {
+	case "rotate-keys":
+		rotateKeys(args[1:])
+	case "import-accounts":
+		importAccounts(args[1:])
+	case "migrate-legacy-doctors":
+		migrateLegacyDoctors()
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func rotateKeys(uses []string) {
+	if len(uses) == 0 {
+		uses = []string{utils.KeyUseAccess, utils.KeyUseRefresh}
+	}
+
+	for _, use := range uses {
+		keyring, err := utils.KeyringFor(use)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		key, err := keyring.Rotate()
+		if err != nil {
+			log.Fatalf("Failed to rotate %s key: %v", use, err)
+		}
+
+		log.Printf("Rotated %s key, new key id %s", use, key.Kid)
+	}
+}
+
+// importAccounts prints the import result as JSON and exits with status 1
+// when a row is invalid.
+func importAccounts(args []string) {
+	flags := flag.NewFlagSet("import-accounts", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "validate the file without creating accounts")
+	credentials := flags.String("credentials", services.CredentialsInvite, "generate 'invite' tokens or initial 'password's")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	var rows []services.ImportRow
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		rows, err = services.ParseImportCSV(file)
+	} else {
+		rows, err = services.ParseImportJSON(file)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := services.ImportAccounts(rows, services.ImportOptions{DryRun: *dryRun, Credentials: *credentials})
+	if err != nil {
+		log.Fatalf("Failed to import accounts: %v", err)
+	}
+
+	notifier.Init()
+	result.SendInvites()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+
+	if !result.Valid {
+		os.Exit(1)
+	}
+	if result.DryRun {
+		log.Printf("All %d rows are valid", len(result.Rows))
+		return
+	}
+	log.Printf("Imported %d accounts", result.Created)
+}
+
+// migrateLegacyDoctors prints the migration result as JSON. Rows without a
+// matching doctor account are kept in the archive table.
+func migrateLegacyDoctors() {
+	if !services.HasLegacyDoctorTable() {
+		log.Printf("No legacy doctors table to migrate")
+		return
+	}
+
+	result, err := services.MigrateLegacyDoctors()
+	if err != nil {
+		log.Fatalf("Failed to migrate legacy doctors: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+
+	log.Printf("Migrated %d of %d legacy doctors", result.Migrated, result.Rows)
+}
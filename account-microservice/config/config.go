@@ -6,9 +6,9 @@ import (
 	"os"
 
 	"account-microservice/models"
+	"account-microservice/utils"
 
 	"github.com/joho/godotenv"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -36,40 +36,168 @@ func InitDB() {
 
 	DB = database
 
-	DB.AutoMigrate(&models.Account{}, &models.Role{}, &models.Specialization{})
+	scopeRoles := !DB.Migrator().HasColumn(&models.Role{}, "hospital_scoped")
+	DB.AutoMigrate(&models.Account{}, &models.Role{}, &models.Permission{}, &models.Specialization{})
 
+	warnLegacyDoctorTable()
+	normalizeRoleNames()
 	initializeAccounts()
+	initializePermissions()
+	if scopeRoles {
+		scopeBuiltInRoles()
+	}
+}
+
+// scopeBuiltInRoles marks the built-in staff roles as hospital-scoped when
+// the column is first added. Their holders need hospital affiliations to
+// keep hospital permissions.
+func scopeBuiltInRoles() {
+	if err := DB.Model(&models.Role{}).Where("name IN ?", models.HospitalScopedRoles).Update("hospital_scoped", true).Error; err != nil {
+		log.Fatalf("Failed to mark hospital-scoped roles: %v", err)
+	}
+	log.Printf("Roles %v are now hospital-scoped", models.HospitalScopedRoles)
+}
+
+// warnLegacyDoctorTable points at the one-off migration while the
+// pre-profile "doctors" table is still present.
+func warnLegacyDoctorTable() {
+	if DB.Migrator().HasTable("doctors") {
+		log.Printf("Legacy doctors table found; run 'main migrate-legacy-doctors' to move it to doctor accounts")
+	}
+}
+
+// normalizeRoleNames lowercases role names created before role checks were
+// made case-insensitive, merging duplicates such as "Admin" and "admin".
+func normalizeRoleNames() {
+	var roles []models.Role
+	if err := DB.Find(&roles).Error; err != nil {
+		log.Fatalf("Failed to load roles: %v", err)
+	}
+
+	for _, role := range roles {
+		name := models.NormalizeRoleName(role.Name)
+		if name == role.Name {
+			continue
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var existing models.Role
+			err := tx.Where("name = ?", name).First(&existing).Error
+			if err == gorm.ErrRecordNotFound {
+				return tx.Model(&role).Update("name", name).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Exec(
+				"INSERT INTO account_roles (account_id, role_id) SELECT account_id, ? FROM account_roles WHERE role_id = ? ON CONFLICT DO NOTHING",
+				existing.ID, role.ID,
+			).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM account_roles WHERE role_id = ?", role.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", role.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&role).Error
+		})
+		if err != nil {
+			log.Fatalf("Failed to normalize role %s: %v", role.Name, err)
+		}
+
+		log.Printf("Role %s renamed to %s", role.Name, name)
+	}
+}
+
+// initializePermissions creates the permission catalogue. Built-in roles
+// without permissions get their defaults, and permissions added in a later
+// release are granted to the built-in roles that include them by default.
+func initializePermissions() {
+	added := make(map[string]bool)
+	for _, permission := range models.Permissions {
+		var existing models.Permission
+		err := DB.Where("name = ?", permission.Name).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			existing = permission
+			if err := DB.Create(&existing).Error; err != nil {
+				log.Fatalf("Failed to create permission %s: %v", permission.Name, err)
+			}
+			added[permission.Name] = true
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Failed to load permission %s: %v", permission.Name, err)
+		}
+		if existing.Description != permission.Description {
+			DB.Model(&existing).Update("description", permission.Description)
+		}
+	}
+
+	for roleName, permissionNames := range models.DefaultRolePermissions {
+		var role models.Role
+		if err := DB.Where("name = ?", roleName).First(&role).Error; err != nil {
+			continue
+		}
+
+		grant := permissionNames
+		if DB.Model(&role).Association("Permissions").Count() > 0 {
+			grant = nil
+			for _, name := range permissionNames {
+				if added[name] {
+					grant = append(grant, name)
+				}
+			}
+		}
+		if len(grant) == 0 {
+			continue
+		}
+
+		var permissions []*models.Permission
+		if err := DB.Where("name IN ?", grant).Find(&permissions).Error; err != nil {
+			log.Fatalf("Failed to load permissions for role %s: %v", roleName, err)
+		}
+		if err := DB.Model(&role).Association("Permissions").Append(permissions); err != nil {
+			log.Fatalf("Failed to grant permissions to role %s: %v", roleName, err)
+		}
+
+		log.Printf("Granted default permissions %v to role %s", grant, roleName)
+	}
 }
 
 func initializeAccounts() {
 	defaultAccounts := []struct {
-		Username string
-		Password string
-		Role     string
+		Username    string
+		Password    string
+		Role        string
+		MFARequired bool
 	}{
-		{"admin", "admin", "Admin"},
-		{"manager", "manager", "Manager"},
-		{"doctor", "doctor", "Doctor"},
-		{"user", "user", "User"},
+		{"admin", "admin", "admin", true},
+		{"manager", "manager", "manager", true},
+		{"doctor", "doctor", "doctor", true},
+		{"user", "user", "user", false},
 	}
 
 	for _, acc := range defaultAccounts {
 		var role models.Role
-		if err := DB.FirstOrCreate(&role, models.Role{Name: acc.Role}).Error; err != nil {
+		if err := DB.Where(models.Role{Name: acc.Role}).Attrs(models.Role{MFARequired: acc.MFARequired}).FirstOrCreate(&role).Error; err != nil {
 			log.Fatalf("Failed to create or find role %s: %v", acc.Role, err)
 		}
 
 		var account models.Account
 		if err := DB.Where("username = ?", acc.Username).Preload("Roles").First(&account).Error; err == gorm.ErrRecordNotFound {
-			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(acc.Password), bcrypt.DefaultCost)
+			hashedPassword, err := utils.HashPassword(acc.Password)
 			if err != nil {
 				log.Fatalf("Failed to hash password for account %s: %v", acc.Username, err)
 			}
 
 			newAccount := models.Account{
-				Username: acc.Username,
-				Password: string(hashedPassword),
-				Roles:    []*models.Role{&role},
+				Username:           acc.Username,
+				Password:           hashedPassword,
+				MustChangePassword: true,
+				Roles:              []*models.Role{&role},
 			}
 
 			if err := DB.Create(&newAccount).Error; err != nil {
@@ -81,6 +209,11 @@ func initializeAccounts() {
 			log.Fatalf("Error checking account %s: %v", acc.Username, err)
 		} else {
 			log.Printf("Account %s already exists", acc.Username)
+
+			if ok, _ := utils.CheckPassword(account.Password, acc.Password); ok && !account.MustChangePassword {
+				DB.Model(&account).Update("must_change_password", true)
+				log.Printf("Account %s still uses its default password and must change it", acc.Username)
+			}
 		}
 	}
 }
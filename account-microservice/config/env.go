@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s value %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+func GetInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s value %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return number
+}
+
+func GetBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s value %q, using %t", key, value, fallback)
+		return fallback
+	}
+	return flag
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"account-microservice/middlewares"
+	"account-microservice/models"
+	"account-microservice/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportSize = 5 << 20
+
+// ImportAccounts creates accounts from a CSV or JSON body, chosen by the
+// Content-Type. Nothing is created unless every row is valid.
+func ImportAccounts(c *gin.Context) {
+	options := services.ImportOptions{
+		DryRun:      c.Query("dryRun") == "true",
+		Credentials: c.DefaultQuery("credentials", services.CredentialsInvite),
+		ActorID:     c.GetUint("account_id"),
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var rows []services.ImportRow
+	var err error
+	if strings.HasPrefix(c.ContentType(), "text/csv") {
+		rows, err = services.ParseImportCSV(body)
+	} else {
+		rows, err = services.ParseImportJSON(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, row := range rows {
+		if len(row.Specializations) > 0 && !middlewares.HasPermission(c, models.PermissionDoctorWrite) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission " + models.PermissionDoctorWrite + " required to import doctors"})
+			return
+		}
+	}
+
+	result, err := services.ImportAccounts(rows, options)
+	if errors.Is(err, services.ErrNoImportRows) || errors.Is(err, services.ErrTooManyImportRows) || errors.Is(err, services.ErrInvalidCredentials) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import accounts"})
+		return
+	}
+
+	switch {
+	case !result.Valid:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		recordSecurityEvent(c, 0, models.SecurityEventAccountsImported, fmt.Sprintf("%d accounts imported with %s credentials", result.Created, options.Credentials))
+		go result.SendInvites()
+		c.JSON(http.StatusCreated, result)
+	}
+}
@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const exportBatchSize = 500
+
+var accountSortColumns = map[string]string{
+	"id":        "accounts.id",
+	"username":  "accounts.username",
+	"lastName":  "accounts.last_name",
+	"createdAt": "accounts.created_at",
+}
+
+type accountSearchItem struct {
+	ID        uint      `json:"id"`
+	LastName  string    `json:"lastName"`
+	FirstName string    `json:"firstName"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Status    string    `json:"status"`
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// accountCursor points just past the last account of a page: its value of
+// the sort column and its ID, which breaks ties.
+type accountCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+type accountQuery struct {
+	filters []func(*gorm.DB) *gorm.DB
+	sort    string
+	desc    bool
+}
+
+// SearchAccounts filters and sorts accounts for administrators. Pages are
+// chained with the opaque nextCursor value.
+func SearchAccounts(c *gin.Context) {
+	query, ok := parseAccountQuery(c)
+	if !ok {
+		return
+	}
+
+	count := 50
+	if value := c.Query("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'count' parameter"})
+			return
+		}
+		count = parsed
+	}
+
+	var after *accountCursor
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := decodeAccountCursor(value)
+		if err == nil {
+			_, err = query.cursorValue(cursor.Value)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'cursor' parameter"})
+			return
+		}
+		after = &cursor
+	}
+
+	var total int64
+	if err := query.filtered().Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
+		return
+	}
+
+	accounts, err := query.page(after, count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
+		return
+	}
+
+	var nextCursor *string
+	if len(accounts) == count {
+		cursor := encodeAccountCursor(query.cursorOf(accounts[len(accounts)-1]))
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":      newAccountSearchItems(accounts),
+		"total":      total,
+		"nextCursor": nextCursor,
+	})
+}
+
+// ExportAccounts streams every account matching the search filters as CSV
+// or JSON, reading them in batches so that large exports stay cheap.
+func ExportAccounts(c *gin.Context) {
+	query, ok := parseAccountQuery(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'format' parameter, expected 'csv' or 'json'"})
+		return
+	}
+
+	recordSecurityEvent(c, 0, models.SecurityEventAccountsExported, fmt.Sprintf("accounts exported as %s with filters %s", format, c.Request.URL.RawQuery))
+
+	filename := fmt.Sprintf("accounts-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+
+	var writeBatch func([]accountSearchItem) error
+	var finish func() error
+	if format == "csv" {
+		writer := csv.NewWriter(c.Writer)
+		writer.Write([]string{"id", "username", "lastName", "firstName", "email", "phone", "status", "roles", "createdAt"})
+		writeBatch = func(items []accountSearchItem) error {
+			for _, item := range items {
+				writer.Write([]string{
+					strconv.FormatUint(uint64(item.ID), 10), item.Username, item.LastName, item.FirstName,
+					item.Email, item.Phone, item.Status, strings.Join(item.Roles, ";"), item.CreatedAt.UTC().Format(time.RFC3339),
+				})
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+		finish = func() error { return nil }
+	} else {
+		encoder := json.NewEncoder(c.Writer)
+		c.Writer.WriteString("[")
+		first := true
+		writeBatch = func(items []accountSearchItem) error {
+			for _, item := range items {
+				if !first {
+					c.Writer.WriteString(",")
+				}
+				first = false
+				if err := encoder.Encode(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		finish = func() error {
+			_, err := c.Writer.WriteString("]\n")
+			return err
+		}
+	}
+
+	var after *accountCursor
+	for {
+		accounts, err := query.page(after, exportBatchSize)
+		if err != nil {
+			log.Printf("Failed to export accounts: %v", err)
+			return
+		}
+		if err := writeBatch(newAccountSearchItems(accounts)); err != nil {
+			log.Printf("Failed to write account export: %v", err)
+			return
+		}
+		c.Writer.Flush()
+
+		if len(accounts) < exportBatchSize {
+			break
+		}
+		cursor := query.cursorOf(accounts[len(accounts)-1])
+		after = &cursor
+	}
+
+	if err := finish(); err != nil {
+		log.Printf("Failed to write account export: %v", err)
+	}
+}
+
+func parseAccountQuery(c *gin.Context) (accountQuery, bool) {
+	query := accountQuery{sort: c.DefaultQuery("sort", "id")}
+	if _, ok := accountSortColumns[query.sort]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'sort' parameter, expected 'id', 'username', 'lastName' or 'createdAt'"})
+		return query, false
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'order' parameter, expected 'asc' or 'desc'"})
+		return query, false
+	}
+
+	if role := c.Query("role"); role != "" {
+		name := models.NormalizeRoleName(role)
+		query.filters = append(query.filters, func(db *gorm.DB) *gorm.DB {
+			return db.Where("EXISTS (SELECT 1 FROM account_roles JOIN roles ON roles.id = account_roles.role_id WHERE account_roles.account_id = accounts.id AND roles.name = ?)", name)
+		})
+	}
+	if name := strings.TrimSpace(c.Query("name")); name != "" {
+		pattern := "%" + name + "%"
+		query.filters = append(query.filters, func(db *gorm.DB) *gorm.DB {
+			return db.Where("accounts.last_name ILIKE ? OR accounts.first_name ILIKE ?", pattern, pattern)
+		})
+	}
+	if username := strings.TrimSpace(c.Query("username")); username != "" {
+		pattern := "%" + username + "%"
+		query.filters = append(query.filters, func(db *gorm.DB) *gorm.DB {
+			return db.Where("accounts.username ILIKE ?", pattern)
+		})
+	}
+	if status := c.Query("status"); status != "" {
+		query.filters = append(query.filters, func(db *gorm.DB) *gorm.DB {
+			return db.Where("accounts.status = ?", status)
+		})
+	}
+	for _, bound := range []struct{ param, condition string }{
+		{"createdFrom", "accounts.created_at >= ?"},
+		{"createdTo", "accounts.created_at < ?"},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid '%s' parameter, expected RFC 3339 time", bound.param)})
+			return query, false
+		}
+		condition := bound.condition
+		query.filters = append(query.filters, func(db *gorm.DB) *gorm.DB {
+			return db.Where(condition, createdAt)
+		})
+	}
+
+	return query, true
+}
+
+func (q accountQuery) filtered() *gorm.DB {
+	db := config.DB.Model(&models.Account{})
+	for _, filter := range q.filters {
+		db = filter(db)
+	}
+	return db
+}
+
+// page returns up to limit accounts following the cursor in sort order.
+func (q accountQuery) page(after *accountCursor, limit int) ([]models.Account, error) {
+	column := accountSortColumns[q.sort]
+	direction, comparison := "ASC", ">"
+	if q.desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	db := q.filtered().Preload("Roles").
+		Order(fmt.Sprintf("%s %s, accounts.id %s", column, direction, direction)).
+		Limit(limit)
+
+	if after != nil {
+		if q.sort == "id" {
+			db = db.Where("accounts.id "+comparison+" ?", after.ID)
+		} else {
+			value, err := q.cursorValue(after.Value)
+			if err != nil {
+				return nil, err
+			}
+			db = db.Where(fmt.Sprintf("(%s, accounts.id) %s (?, ?)", column, comparison), value, after.ID)
+		}
+	}
+
+	var accounts []models.Account
+	err := db.Find(&accounts).Error
+	return accounts, err
+}
+
+func (q accountQuery) cursorOf(account models.Account) accountCursor {
+	cursor := accountCursor{ID: account.ID}
+	switch q.sort {
+	case "username":
+		cursor.Value = account.Username
+	case "lastName":
+		cursor.Value = account.LastName
+	case "createdAt":
+		cursor.Value = account.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+func (q accountQuery) cursorValue(value string) (interface{}, error) {
+	if q.sort == "createdAt" {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
+
+func encodeAccountCursor(cursor accountCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAccountCursor(value string) (accountCursor, error) {
+	var cursor accountCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+func newAccountSearchItems(accounts []models.Account) []accountSearchItem {
+	items := make([]accountSearchItem, 0, len(accounts))
+	for _, account := range accounts {
+		roles := make([]string, 0, len(account.Roles))
+		for _, role := range account.Roles {
+			roles = append(roles, role.Name)
+		}
+		items = append(items, accountSearchItem{
+			ID:        account.ID,
+			LastName:  account.LastName,
+			FirstName: account.FirstName,
+			Username:  account.Username,
+			Email:     account.Email,
+			Phone:     account.Phone,
+			Status:    account.Status,
+			Roles:     roles,
+			CreatedAt: account.CreatedAt,
+		})
+	}
+	return items
+}
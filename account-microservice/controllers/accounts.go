@@ -3,12 +3,15 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"account-microservice/config"
 	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -16,7 +19,7 @@ func GetCurrentAccount(c *gin.Context) {
 	accountID := c.GetUint("account_id")
 
 	var account models.Account
-	if err := config.DB.Preload("Roles").First(&account, accountID).Error; err != nil {
+	if err := config.DB.Preload("Roles.Permissions").First(&account, accountID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
 		return
 	}
@@ -30,6 +33,8 @@ func UpdateCurrentAccount(c *gin.Context) {
 	var input struct {
 		LastName  string `json:"lastName"`
 		FirstName string `json:"firstName"`
+		Email     string `json:"email" binding:"omitempty,email"`
+		Phone     string `json:"phone"`
 		Password  string `json:"password"`
 	}
 
@@ -50,20 +55,32 @@ func UpdateCurrentAccount(c *gin.Context) {
 	if input.FirstName != "" {
 		account.FirstName = input.FirstName
 	}
+	previousEmail, previousPhone, wasUnverified := account.Email, account.Phone, account.Unverified
+	if !applyContactChanges(c, &account, input.Email, input.Phone) {
+		return
+	}
 	if input.Password != "" {
-		passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Password encryption failed"})
+		if !setPassword(c, &account, input.Password) {
 			return
 		}
-		account.Password = string(passwordHash)
-	}
+		account.MustChangePassword = false
 
-	if err := config.DB.Save(&account).Error; err != nil {
+		if err := saveAccountPassword(config.DB, &account); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update account"})
+			return
+		}
+	} else if err := config.DB.Save(&account).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update account"})
 		return
 	}
 
+	if !revokeIfUnverified(c, account, wasUnverified) {
+		return
+	}
+	if account.Email != previousEmail || account.Phone != previousPhone {
+		go sendVerificationCodes(account)
+	}
+
 	c.Status(http.StatusOK)
 }
 
@@ -93,6 +110,10 @@ func GetAllAccounts(c *gin.Context) {
 	var accounts []models.Account
 	query := config.DB.Preload("Roles")
 
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	if fromStr != "" && countStr != "" {
 		query = query.Offset(from).Limit(count)
 	}
@@ -110,6 +131,8 @@ func CreateAccount(c *gin.Context) {
 		LastName  string   `json:"lastName" binding:"required"`
 		FirstName string   `json:"firstName" binding:"required"`
 		Username  string   `json:"username" binding:"required"`
+		Email     string   `json:"email" binding:"omitempty,email"`
+		Phone     string   `json:"phone"`
 		Password  string   `json:"password" binding:"required"`
 		Roles     []string `json:"roles" binding:"required"`
 	}
@@ -119,35 +142,32 @@ func CreateAccount(c *gin.Context) {
 		return
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	roles, err := findRoles(input.Roles)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password encryption failed"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var roles []*models.Role
-	for _, roleName := range input.Roles {
-		var role models.Role
-		if err := config.DB.FirstOrCreate(&role, models.Role{Name: roleName}).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
-			return
-		}
-		roles = append(roles, &role)
-	}
-
 	account := models.Account{
-		LastName:  input.LastName,
-		FirstName: input.FirstName,
-		Username:  input.Username,
-		Password:  string(passwordHash),
-		Roles:     roles,
+		LastName:           input.LastName,
+		FirstName:          input.FirstName,
+		Username:           input.Username,
+		MustChangePassword: true,
+		Roles:              roles,
+	}
+	if !applyContactChanges(c, &account, input.Email, input.Phone) {
+		return
+	}
+	if !setPassword(c, &account, input.Password) {
+		return
 	}
 
-	if err := config.DB.Create(&account).Error; err != nil {
+	if err := createAccount(config.DB, &account); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
 		return
 	}
 
+	recordRoleChanges(c, account.ID, nil, roles)
 	c.Status(http.StatusCreated)
 }
 
@@ -158,6 +178,8 @@ func UpdateAccount(c *gin.Context) {
 		LastName  string   `json:"lastName"`
 		FirstName string   `json:"firstName"`
 		Username  string   `json:"username"`
+		Email     string   `json:"email" binding:"omitempty,email"`
+		Phone     string   `json:"phone"`
 		Password  string   `json:"password"`
 		Roles     []string `json:"roles"`
 	}
@@ -172,6 +194,7 @@ func UpdateAccount(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Account not found"})
 		return
 	}
+	previous := services.AccountAuditFields(account)
 
 	if input.LastName != "" {
 		account.LastName = input.LastName
@@ -182,46 +205,176 @@ func UpdateAccount(c *gin.Context) {
 	if input.Username != "" {
 		account.Username = input.Username
 	}
+	previousEmail, previousPhone, wasUnverified := account.Email, account.Phone, account.Unverified
+	if !applyContactChanges(c, &account, input.Email, input.Phone) {
+		return
+	}
 	if input.Password != "" {
-		passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Password encryption failed"})
+		if !setPassword(c, &account, input.Password) {
 			return
 		}
-		account.Password = string(passwordHash)
+		account.MustChangePassword = true
 	}
+	var roles []*models.Role
 	if len(input.Roles) > 0 {
-		var roles []*models.Role
-		for _, roleName := range input.Roles {
-			var role models.Role
-			if err := config.DB.FirstOrCreate(&role, models.Role{Name: roleName}).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
-				return
+		var err error
+		roles, err = findRoles(input.Roles)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	previousRoles := account.Roles
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Roles").Save(&account).Error; err != nil {
+			return err
+		}
+		if input.Password != "" {
+			if err := services.RecordPasswordHistory(tx, account.ID, account.Password); err != nil {
+				return err
 			}
-			roles = append(roles, &role)
 		}
+		if roles == nil {
+			return nil
+		}
+		return tx.Model(&account).Association("Roles").Replace(roles)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update account"})
+		return
+	}
+
+	if roles != nil {
 		account.Roles = roles
 	}
+	before, after := services.AuditDiff(previous, services.AccountAuditFields(account))
+	if input.Password != "" {
+		after["password"] = "changed"
+	}
+	recordAuditEvent(c, requestActor(c), account.ID, models.AuditAccountUpdated, before, after)
 
-	if err := config.DB.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&account).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update account"})
+	if !revokeIfUnverified(c, account, wasUnverified) {
 		return
 	}
+	if account.Email != previousEmail || account.Phone != previousPhone {
+		go sendVerificationCodes(account)
+	}
+
+	if roles != nil {
+		recordRoleChanges(c, account.ID, previousRoles, roles)
+		if err := services.RevokeAccount(account.ID, "roles changed"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke existing tokens"})
+			return
+		}
+	}
 
 	c.Status(http.StatusOK)
 }
 
 func DeleteAccount(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var account models.Account
+	found := config.DB.Preload("Roles").First(&account, id).Error == nil
 
 	if err := config.DB.Where("id = ?", id).Delete(&models.Account{}).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete account"})
 		return
 	}
 
+	if found {
+		recordAuditEvent(c, requestActor(c), account.ID, models.AuditAccountDeleted, services.AccountAuditFields(account), nil)
+	}
+
+	if err := config.DB.Model(&models.Token{}).Where("account_id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh tokens"})
+		return
+	}
+
+	if err := services.RevokeAccount(uint(id), "account deleted"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke existing tokens"})
+		return
+	}
+
 	c.Status(http.StatusOK)
 }
 
+// UpdateAccountStatus suspends, reactivates or marks an account as pending.
+// A change without an effective date applies immediately; otherwise the
+// status scheduler applies it when the date arrives. Blocking statuses
+// revoke every session of the account once applied.
+func UpdateAccountStatus(c *gin.Context) {
+	var input struct {
+		Status      string     `json:"status" binding:"required,oneof=active suspended pending"`
+		Reason      string     `json:"reason" binding:"max=512"`
+		EffectiveAt *time.Time `json:"effectiveAt"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accountID, ok := findAccountID(c)
+	if !ok {
+		return
+	}
+	if accountID == c.GetUint("account_id") && input.Status != models.AccountActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot suspend your own account"})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, accountID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	now := time.Now()
+	previous := account.EffectiveStatus(now)
+	if input.EffectiveAt != nil && !input.EffectiveAt.After(now) {
+		input.EffectiveAt = nil
+	}
+
+	account.AppliedStatus = previous
+	account.Status = input.Status
+	account.StatusReason = strings.TrimSpace(input.Reason)
+	account.StatusEffectiveAt = input.EffectiveAt
+	if err := config.DB.Model(&account).Select("status", "status_reason", "status_effective_at", "applied_status").Updates(&account).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account status"})
+		return
+	}
+
+	if input.EffectiveAt == nil {
+		if err := services.ApplyAccountStatus(account); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+	}
+
+	details := "status " + previous + " -> " + account.Status
+	if input.EffectiveAt != nil {
+		details += " effective " + input.EffectiveAt.UTC().Format(time.RFC3339)
+	}
+	if account.StatusReason != "" {
+		details += ": " + account.StatusReason
+	}
+	recordSecurityEvent(c, account.ID, models.SecurityEventStatusChanged, details)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                account.ID,
+		"status":            account.Status,
+		"statusReason":      account.StatusReason,
+		"statusEffectiveAt": account.StatusEffectiveAt,
+		"effectiveStatus":   account.EffectiveStatus(time.Now()),
+	})
+}
+
 func CheckUserRole(c *gin.Context) {
 	accountIDStr := c.Param("id")
 	
@@ -234,32 +387,62 @@ func CheckUserRole(c *gin.Context) {
 	var account models.Account
 
 	if err := config.DB.Preload("Roles").First(&account, accountID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
 		return
 	}
 
-	if !containsRole2(account.Roles, "user") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	permissions, err := services.AccountPermissions(account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	c.JSON(http.StatusOK, gin.H{
+		"id":          account.ID,
+		"roles":       account.Roles,
+		"permissions": permissions,
+	})
 }
 
-func containsRole2(roles []*models.Role, targetRole string) bool {
-	for _, role := range roles {
-		if role.Name == targetRole {
-			return true
+// applyContactChanges sets a new email or phone and clears its verified
+// flag when it differs from the current value. Clearing a verified flag
+// marks the whole account unverified until the new contact is confirmed.
+func applyContactChanges(c *gin.Context, account *models.Account, email, phone string) bool {
+	if email != "" && !strings.EqualFold(email, account.Email) {
+		account.Email = email
+		if account.EmailVerified {
+			account.Unverified = true
 		}
+		account.EmailVerified = false
 	}
-	return false
-}
 
-func containsRole(roles []string, targetRole string) bool {
-	for _, role := range roles {
-		if role == targetRole {
-			return true
+	if phone != "" {
+		normalized, err := utils.NormalizePhone(phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
 		}
+		if normalized != account.Phone {
+			account.Phone = normalized
+			if account.PhoneVerified {
+				account.Unverified = true
+			}
+			account.PhoneVerified = false
+		}
+	}
+	return true
+}
+
+// revokeIfUnverified revokes the account's access tokens when a contact
+// change has just made it unverified, so that no token keeps the verified
+// claim.
+func revokeIfUnverified(c *gin.Context, account models.Account, wasUnverified bool) bool {
+	if wasUnverified || !account.Unverified {
+		return true
+	}
+	if err := services.RevokeAccount(account.ID, "contact changed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke existing tokens"})
+		return false
 	}
-	return false
+	return true
 }
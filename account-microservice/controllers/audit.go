@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAuditEvent appends an audit event for the request. Failures are
+// logged and never fail the request itself.
+func recordAuditEvent(c *gin.Context, actorID, targetID uint, action string, before, after map[string]interface{}) {
+	event := models.AuditEvent{
+		Action:    action,
+		ActorID:   actorID,
+		TargetID:  targetID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Before:    before,
+		After:     after,
+	}
+
+	if err := config.DB.Create(&event).Error; err != nil {
+		log.Printf("Failed to record audit event %s for account %d: %v", action, targetID, err)
+	}
+}
+
+// GetAuditEvents lists audit events, newest first. archived=true searches
+// the events moved out by the retention job instead.
+func GetAuditEvents(c *gin.Context) {
+	archived := c.Query("archived") == "true"
+
+	query := config.DB.Model(&models.AuditEvent{})
+	if archived {
+		query = config.DB.Model(&models.ArchivedAuditEvent{})
+	}
+	query = query.Order("created_at DESC, id DESC")
+
+	if actions := c.Query("action"); actions != "" {
+		query = query.Where("action IN ?", strings.Split(actions, ","))
+	}
+	for param, column := range map[string]string{"actorId": "actor_id", "targetId": "target_id"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid '" + param + "' parameter"})
+			return
+		}
+		query = query.Where(column+" = ?", id)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'since' parameter"})
+			return
+		}
+		query = query.Where("created_at >= ?", since)
+	}
+	if value := c.Query("until"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'until' parameter"})
+			return
+		}
+		query = query.Where("created_at < ?", until)
+	}
+
+	from, count := 0, 100
+	if value := c.Query("from"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' parameter"})
+			return
+		}
+		from = parsed
+	}
+	if value := c.Query("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'count' parameter"})
+			return
+		}
+		count = parsed
+	}
+	query = query.Offset(from).Limit(count)
+
+	var err error
+	var events interface{}
+	if archived {
+		var rows []models.ArchivedAuditEvent
+		err = query.Find(&rows).Error
+		events = rows
+	} else {
+		var rows []models.AuditEvent
+		err = query.Find(&rows).Error
+		events = rows
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
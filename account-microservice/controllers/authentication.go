@@ -3,15 +3,16 @@ package controllers
 import (
 	"account-microservice/config"
 	"account-microservice/models"
+	"account-microservice/services"
 	"account-microservice/utils"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
 func SignUp(c *gin.Context) {
@@ -19,6 +20,8 @@ func SignUp(c *gin.Context) {
 		LastName  string `json:"lastName" binding:"required"`
 		FirstName string `json:"firstName" binding:"required"`
 		Username  string `json:"username" binding:"required"`
+		Email     string `json:"email" binding:"omitempty,email"`
+		Phone     string `json:"phone"`
 		Password  string `json:"password" binding:"required"`
 	}
 
@@ -27,10 +30,13 @@ func SignUp(c *gin.Context) {
 		return
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password encryption failed"})
-		return
+	if input.Phone != "" {
+		phone, err := utils.NormalizePhone(input.Phone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		input.Phone = phone
 	}
 
 	var userRole models.Role
@@ -40,25 +46,33 @@ func SignUp(c *gin.Context) {
 	}
 
 	account := models.Account{
-		LastName:  input.LastName,
-		FirstName: input.FirstName,
-		Username:  input.Username,
-		Password:  string(passwordHash),
-		Roles:     []*models.Role{&userRole},
+		LastName:   input.LastName,
+		FirstName:  input.FirstName,
+		Username:   input.Username,
+		Email:      input.Email,
+		Phone:      input.Phone,
+		Unverified: true,
+		Roles:      []*models.Role{&userRole},
+	}
+	if !setPassword(c, &account, input.Password) {
+		return
 	}
 
-	if err := config.DB.Create(&account).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
+	if err := createAccount(config.DB, &account); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username, email or phone already exists"})
 		return
 	}
 
+	go sendVerificationCodes(account)
+
 	c.Status(http.StatusCreated)
 }
 
 func SignIn(c *gin.Context) {
 	var input struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"deviceName"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -66,50 +80,122 @@ func SignIn(c *gin.Context) {
 		return
 	}
 
-	var account models.Account
-	if err := config.DB.Preload("Roles").Where("username = ?", input.Username).First(&account).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	account, signInErr := checkCredentials(c, input.Username, input.Password)
+	if signInErr != nil {
+		respondSignInError(c, signInErr)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	if account.TOTPEnabled || requiresMFA(account) {
+		respondWithMFAChallenge(c, account, input.DeviceName)
 		return
 	}
 
-	var roles []string
-	for _, role := range account.Roles {
-		roles = append(roles, role.Name)
-	}
+	completeSignIn(c, account, input.DeviceName, nil)
+}
+
+type signInError struct {
+	status     int
+	message    string
+	retryAfter time.Duration
+}
+
+// checkCredentials verifies a username and password with brute-force
+// protection and upgrades legacy password hashes.
+func checkCredentials(c *gin.Context, username, password string) (models.Account, *signInError) {
+	userKey := services.UserLockoutKey(username)
+	ipKey := services.IPLockoutKey(c.ClientIP())
 
-	accessToken, err := utils.GenerateAccessToken(account.ID, roles)
+	retryAfter, err := services.LoginRetryAfter(userKey, ipKey)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate access token"})
-		return
+		return models.Account{}, &signInError{status: http.StatusInternalServerError, message: "Failed to check sign-in attempts"}
+	}
+	if retryAfter > 0 {
+		auditSignInFailure(c, 0, username, "locked out")
+		return models.Account{}, &signInError{status: http.StatusTooManyRequests, message: "Too many failed sign-in attempts, try again later", retryAfter: retryAfter}
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(account.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate refresh token"})
-		return
+	var account models.Account
+	if err := config.DB.Preload("Roles").Where("username = ?", username).First(&account).Error; err != nil {
+		recordLoginFailure(c, 0, userKey, ipKey)
+		auditSignInFailure(c, 0, username, "unknown username")
+		return models.Account{}, &signInError{status: http.StatusUnauthorized, message: "Invalid username or password"}
 	}
 
-	token := models.Token{
-		Token:     refreshToken,
-		AccountID: account.ID,
-		ExpiresAt: time.Now().Add(time.Hour * 24 * 7),
+	passwordOK, needsRehash := utils.CheckPassword(account.Password, password)
+	if !passwordOK {
+		recordLoginFailure(c, account.ID, userKey, ipKey)
+		auditSignInFailure(c, account.ID, username, "invalid password")
+		return models.Account{}, &signInError{status: http.StatusUnauthorized, message: "Invalid username or password"}
+	}
+	if needsRehash {
+		upgradePasswordHash(&account, password)
 	}
-	config.DB.Create(&token)
 
-	c.JSON(http.StatusOK, gin.H{
-		"accessToken":  accessToken,
-		"refreshToken": refreshToken,
+	// With a second factor pending, failures are cleared once the code is
+	// accepted so that invalid codes keep counting towards the lockout.
+	if !account.TOTPEnabled && !requiresMFA(account) {
+		if err := services.ClearLoginFailures(userKey); err != nil {
+			log.Printf("Failed to clear sign-in failures for %s: %v", userKey, err)
+		}
+	}
+
+	if err := services.CheckAccountStatus(account); err != nil {
+		auditSignInFailure(c, account.ID, username, "account "+account.EffectiveStatus(time.Now()))
+		return models.Account{}, &signInError{status: http.StatusForbidden, message: err.Error()}
+	}
+
+	return account, nil
+}
+
+func auditSignInFailure(c *gin.Context, accountID uint, username, reason string) {
+	recordAuditEvent(c, 0, accountID, models.AuditSignInFailed, nil, map[string]interface{}{
+		"username": username,
+		"reason":   reason,
 	})
 }
 
+func respondSignInError(c *gin.Context, err *signInError) {
+	if err.retryAfter > 0 {
+		respondTooManyAttempts(c, err.retryAfter)
+		return
+	}
+	c.JSON(err.status, gin.H{"error": err.message})
+}
+
 func SignOut(c *gin.Context) {
 	accountID := c.GetUint("account_id")
-	config.DB.Where("account_id = ?", accountID).Delete(&models.Token{})
+	sessionID := c.GetUint("session_id")
+
+	claims := c.MustGet("claims").(jwt.MapClaims)
+	if jti, ok := claims["jti"].(string); ok {
+		expiresAt := time.Unix(int64(claims["exp"].(float64)), 0)
+		if err := services.RevokeToken(jti, expiresAt, "signed out"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out"})
+			return
+		}
+	}
+
+	// Ending an impersonation must not sign the impersonated account out.
+	if c.GetUint("impersonator_id") != 0 {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	if sessionID == 0 {
+		config.DB.Where("account_id = ?", accountID).Delete(&models.Token{})
+		c.Status(http.StatusOK)
+		return
+	}
+
+	var session models.Session
+	if err := config.DB.Where("id = ? AND account_id = ?", sessionID, accountID).First(&session).Error; err == nil {
+		if err := revokeSession(&session); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out"})
+			return
+		}
+	}
+
 	c.Status(http.StatusOK)
 }
 
@@ -120,12 +206,36 @@ func ValidateToken(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.ValidateToken(accessToken, os.Getenv("ACCESS_TOKEN_SECRET"))
+	token, err := utils.ValidateAccessToken(accessToken)
 	isValid := err == nil && token.Valid
 
+	if isValid {
+		claims := token.Claims.(jwt.MapClaims)
+		revoked, err := services.IsRevoked(claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token revocation"})
+			return
+		}
+		isValid = !revoked
+
+		if accountID, ok := claims["account_id"].(float64); ok && isValid {
+			isValid = services.CheckAccountStatusByID(uint(accountID)) == nil
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"isValid": isValid})
 }
 
+func GetRevocations(c *gin.Context) {
+	revocations, err := services.ActiveRevocations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revocations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revocations)
+}
+
 func RefreshToken(c *gin.Context) {
 	var input struct {
 		RefreshToken string `json:"refreshToken" binding:"required"`
@@ -136,72 +246,236 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	secret := os.Getenv("REFRESH_TOKEN_SECRET")
-	if secret == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Refresh token secret not set"})
+	tokens, tokenErr := rotateRefreshToken(c, input.RefreshToken, "")
+	if tokenErr != nil {
+		response := gin.H{"error": tokenErr.message}
+		if tokenErr.details != "" {
+			response["details"] = tokenErr.details
+		}
+		c.JSON(tokenErr.status, response)
 		return
 	}
 
-	token, err := jwt.Parse(input.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("REFRESH_TOKEN_SECRET")), nil
+	c.JSON(http.StatusOK, gin.H{
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
 	})
+}
+
+type issuedTokens struct {
+	Account      models.Account
+	Session      models.Session
+	AccessToken  string
+	RefreshToken string
+}
+
+type tokenError struct {
+	status  int
+	message string
+	details string
+}
+
+// rotateRefreshToken exchanges a refresh token for a new token pair. The
+// token must belong to a session of clientID, or a first-party session when
+// clientID is empty.
+func rotateRefreshToken(c *gin.Context, refreshToken string, clientID string) (issuedTokens, *tokenError) {
+	token, err := utils.ValidateRefreshToken(refreshToken)
+	if err != nil || !token.Valid {
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Invalid token or expired"}
+	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid token or expired",
-		})
-		return
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Invalid token or expired"}
 	}
 
 	accountIDFloat, ok := claims["account_id"].(float64)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims: account_id not found"})
-		return
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Invalid token claims: account_id not found"}
 	}
 	accountID := uint(accountIDFloat)
 
 	var storedToken models.Token
-	if err := config.DB.Where("token = ? AND account_id = ?", input.RefreshToken, accountID).First(&storedToken).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token in DB", "details": err.Error()})
-		return
+	if err := config.DB.Where("token = ? AND account_id = ?", refreshToken, accountID).First(&storedToken).Error; err != nil {
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Invalid or expired refresh token in DB", details: err.Error()}
+	}
+
+	if storedToken.RotatedAt != nil {
+		revokeTokenFamily(c, storedToken)
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Refresh token has already been used"}
+	}
+
+	if storedToken.RevokedAt != nil {
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Refresh token has been revoked"}
 	}
 
 	var account models.Account
 	if err := config.DB.Preload("Roles").First(&account, accountID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found", "details": err.Error()})
-		return
+		return issuedTokens{}, &tokenError{status: http.StatusInternalServerError, message: "Account not found", details: err.Error()}
+	}
+
+	if err := services.CheckAccountStatus(account); err != nil {
+		return issuedTokens{}, &tokenError{status: http.StatusForbidden, message: err.Error()}
+	}
+
+	var session models.Session
+	if err := config.DB.Where("family_id = ?", storedToken.FamilyID).First(&session).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return issuedTokens{}, &tokenError{status: http.StatusInternalServerError, message: "Failed to load session", details: err.Error()}
+	}
+	if session.ClientID != clientID {
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Refresh token was issued to a different client"}
+	}
+
+	newAccessToken, err := generateAccessToken(account, session)
+	if err != nil {
+		return issuedTokens{}, &tokenError{status: http.StatusInternalServerError, message: "Could not generate access token", details: err.Error()}
+	}
+
+	newRefreshToken, err := utils.GenerateRefreshToken(account.ID)
+	if err != nil {
+		return issuedTokens{}, &tokenError{status: http.StatusInternalServerError, message: "Could not generate refresh token", details: err.Error()}
+	}
+
+	now := time.Now()
+	newToken := models.Token{
+		Token:     newRefreshToken,
+		AccountID: account.ID,
+		FamilyID:  storedToken.FamilyID,
+		ExpiresAt: now.Add(utils.RefreshTokenLifetime),
+	}
+
+	var reused bool
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Token{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", storedToken.ID).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return nil
+		}
+		if err := tx.Create(&newToken).Error; err != nil {
+			return err
+		}
+		if session.ID == 0 {
+			return nil
+		}
+		return tx.Model(&session).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"ip":           c.ClientIP(),
+			"user_agent":   c.Request.UserAgent(),
+		}).Error
+	})
+	if err != nil {
+		return issuedTokens{}, &tokenError{status: http.StatusInternalServerError, message: "Failed to update refresh token", details: err.Error()}
+	}
+	if reused {
+		revokeTokenFamily(c, storedToken)
+		return issuedTokens{}, &tokenError{status: http.StatusUnauthorized, message: "Refresh token has already been used"}
+	}
+
+	recordAuditEvent(c, account.ID, account.ID, models.AuditTokenRefreshed,
+		map[string]interface{}{"tokenId": storedToken.ID},
+		map[string]interface{}{"tokenId": newToken.ID, "familyId": newToken.FamilyID, "sessionId": session.ID, "clientId": clientID})
+
+	return issuedTokens{
+		Account:      account,
+		Session:      session,
+		AccessToken:  newAccessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+func revokeTokenFamily(c *gin.Context, token models.Token) {
+	var session models.Session
+	if err := config.DB.Where("family_id = ?", token.FamilyID).First(&session).Error; err == nil {
+		if err := revokeSession(&session); err != nil {
+			log.Printf("Failed to revoke session %d: %v", session.ID, err)
+		}
+	} else if err := config.DB.Model(&models.Token{}).
+		Where("family_id = ? AND revoked_at IS NULL", token.FamilyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		log.Printf("Failed to revoke refresh token family %s: %v", token.FamilyID, err)
+	}
+
+	recordSecurityEvent(c, token.AccountID, models.SecurityEventRefreshTokenReuse, "refresh token family "+token.FamilyID+" revoked")
+	recordAuditEvent(c, 0, token.AccountID, models.AuditRefreshTokenReused, nil, map[string]interface{}{
+		"tokenId":  token.ID,
+		"familyId": token.FamilyID,
+	})
+}
+
+func signInResponse(c *gin.Context, account models.Account, deviceName string) (gin.H, error) {
+	session, refreshToken, err := startSession(c, account, deviceName)
+	if err != nil {
+		return nil, errors.New("Could not generate refresh token")
+	}
+
+	accessToken, err := generateAccessToken(account, session)
+	if err != nil {
+		return nil, errors.New("Could not generate access token")
+	}
+
+	recordAuditEvent(c, account.ID, account.ID, models.AuditSignIn, nil, map[string]interface{}{
+		"sessionId":  session.ID,
+		"deviceName": session.DeviceName,
+	})
+
+	return gin.H{
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+	}, nil
+}
+
+func generateAccessToken(account models.Account, session models.Session) (string, error) {
+	claims, err := accessClaims(account)
+	if err != nil {
+		return "", err
+	}
+
+	// Tokens of OAuth clients only carry the OpenID scopes they were granted,
+	// never the roles, permissions, dependents or hospitals of the account.
+	if session.ClientID != "" {
+		claims = utils.AccessClaims{AccountID: account.ID, Verified: claims.Verified}
 	}
 
+	claims.SessionID = session.ID
+	claims.ClientID = session.ClientID
+	claims.Scope = session.Scope
+	return utils.GenerateAccessToken(claims)
+}
+
+// accessClaims collects the claims describing the account itself. The roles
+// must be preloaded.
+func accessClaims(account models.Account) (utils.AccessClaims, error) {
 	var roles []string
 	for _, role := range account.Roles {
 		roles = append(roles, role.Name)
 	}
 
-	newAccessToken, err := utils.GenerateAccessToken(account.ID, roles)
+	permissions, err := services.AccountPermissions(account)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate access token", "details": err.Error()})
-		return
+		return utils.AccessClaims{}, err
 	}
 
-	newRefreshToken, err := utils.GenerateRefreshToken(account.ID)
+	dependents, err := services.ActiveDependents(account.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate refresh token", "details": err.Error()})
-		return
+		return utils.AccessClaims{}, err
 	}
 
-	storedToken.Token = newRefreshToken
-	storedToken.ExpiresAt = time.Now().Add(time.Hour * 24 * 7)
-	if err := config.DB.Save(&storedToken).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update refresh token", "details": err.Error()})
-		return
+	hospitals, err := services.HospitalAccess(account.ID)
+	if err != nil {
+		return utils.AccessClaims{}, err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"accessToken":  newAccessToken,
-		"refreshToken": newRefreshToken,
-	})
+	return utils.AccessClaims{
+		AccountID:   account.ID,
+		Roles:       roles,
+		Permissions: permissions,
+		Dependents:  dependents,
+		Hospitals:   hospitals,
+		Verified:    !account.Unverified,
+	}, nil
 }
@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxDoctorPhotoSize = 2 << 20
+
+var doctorPhotoTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+type doctorProfileResponse struct {
+	models.DoctorProfile
+	YearsOfExperience int    `json:"yearsOfExperience"`
+	PhotoURL          string `json:"photoUrl,omitempty"`
+}
+
+type expiringLicence struct {
+	ID               uint      `json:"id"`
+	LastName         string    `json:"lastName"`
+	FirstName        string    `json:"firstName"`
+	LicenceNumber    string    `json:"licenceNumber"`
+	LicenceExpiresAt time.Time `json:"licenceExpiresAt"`
+	Expired          bool      `json:"expired"`
+}
+
+func UpdateDoctorProfile(c *gin.Context) {
+	var input struct {
+		Biography         *string    `json:"biography" binding:"omitempty,max=4000"`
+		LicenceNumber     *string    `json:"licenceNumber" binding:"omitempty,max=64"`
+		LicenceExpiresAt  *time.Time `json:"licenceExpiresAt"`
+		PracticeStartYear *int       `json:"practiceStartYear"`
+		Languages         []string   `json:"languages" binding:"omitempty,max=20,dive,min=2,max=32"`
+		AcademicDegree    *string    `json:"academicDegree" binding:"omitempty,max=128"`
+		Public            *bool      `json:"public"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctor, ok := findDoctor(c)
+	if !ok {
+		return
+	}
+
+	if !canManageDoctorProfile(c, doctor.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own profile"})
+		return
+	}
+	if (input.LicenceNumber != nil || input.LicenceExpiresAt != nil) && !canManageDoctors(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission " + models.PermissionDoctorWrite + " required to change licence details"})
+		return
+	}
+	if input.PracticeStartYear != nil && (*input.PracticeStartYear < 1940 || *input.PracticeStartYear > time.Now().Year()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'practiceStartYear'"})
+		return
+	}
+
+	profile, err := loadDoctorProfile(doctor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve doctor profile"})
+		return
+	}
+
+	if input.Biography != nil {
+		profile.Biography = strings.TrimSpace(*input.Biography)
+	}
+	if input.LicenceNumber != nil {
+		profile.LicenceNumber = strings.ToUpper(strings.TrimSpace(*input.LicenceNumber))
+	}
+	if input.LicenceExpiresAt != nil {
+		profile.LicenceExpiresAt = input.LicenceExpiresAt
+	}
+	if input.PracticeStartYear != nil {
+		profile.PracticeStartYear = *input.PracticeStartYear
+	}
+	if input.Languages != nil {
+		profile.Languages = normalizeLanguages(input.Languages)
+	}
+	if input.AcademicDegree != nil {
+		profile.AcademicDegree = strings.TrimSpace(*input.AcademicDegree)
+	}
+	if input.Public != nil {
+		profile.Public = *input.Public
+	}
+
+	if profile.LicenceNumber != "" {
+		var count int64
+		config.DB.Model(&models.DoctorProfile{}).Where("licence_number = ? AND account_id <> ?", profile.LicenceNumber, doctor.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Licence number is already registered to another doctor"})
+			return
+		}
+	}
+
+	if err := config.DB.Save(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update doctor profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newDoctorProfileResponse(profile))
+}
+
+func UploadDoctorPhoto(c *gin.Context) {
+	doctor, ok := findDoctor(c)
+	if !ok {
+		return
+	}
+
+	if !canManageDoctorProfile(c, doctor.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own profile"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDoctorPhotoSize+1<<16)
+	file, err := c.FormFile("photo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Form field 'photo' is required"})
+		return
+	}
+	if file.Size > maxDoctorPhotoSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Photo must not exceed 2 MB"})
+		return
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read photo"})
+		return
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxDoctorPhotoSize+1))
+	if err != nil || len(data) > maxDoctorPhotoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read photo"})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !utils.ContainsString(doctorPhotoTypes, contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Photo must be a JPEG, PNG or WebP image"})
+		return
+	}
+
+	profile, err := loadDoctorProfile(doctor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve doctor profile"})
+		return
+	}
+	profile.HasPhoto = true
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		photo := models.DoctorPhoto{AccountID: doctor.ID, ContentType: contentType, Data: data}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"content_type", "data", "updated_at"}),
+		}).Create(&photo).Error; err != nil {
+			return err
+		}
+		return tx.Save(&profile).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save photo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newDoctorProfileResponse(profile))
+}
+
+func DeleteDoctorPhoto(c *gin.Context) {
+	doctor, ok := findDoctor(c)
+	if !ok {
+		return
+	}
+
+	if !canManageDoctorProfile(c, doctor.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own profile"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", doctor.ID).Delete(&models.DoctorPhoto{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.DoctorProfile{}).Where("account_id = ?", doctor.ID).Update("has_photo", false).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func GetDoctorPhoto(c *gin.Context) {
+	doctor, ok := findDoctor(c)
+	if !ok {
+		return
+	}
+
+	profile, err := loadDoctorProfile(doctor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve doctor profile"})
+		return
+	}
+	if !profile.HasPhoto || !canViewDoctorProfile(c, profile) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+
+	var photo models.DoctorPhoto
+	if err := config.DB.First(&photo, "account_id = ?", doctor.ID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, photo.ContentType, photo.Data)
+}
+
+// GetExpiringLicences lists doctors whose licence expires within the given
+// number of days, including licences that have already expired.
+func GetExpiringLicences(c *gin.Context) {
+	days := 30
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'days' parameter"})
+			return
+		}
+		days = parsed
+	}
+
+	now := time.Now()
+	var licences []expiringLicence
+	err := config.DB.Table("doctor_profiles").
+		Select("accounts.id, accounts.last_name, accounts.first_name, doctor_profiles.licence_number, doctor_profiles.licence_expires_at").
+		Joins("JOIN accounts ON accounts.id = doctor_profiles.account_id AND accounts.deleted_at IS NULL").
+		Where("doctor_profiles.licence_expires_at IS NOT NULL AND doctor_profiles.licence_expires_at < ?", now.AddDate(0, 0, days)).
+		Order("doctor_profiles.licence_expires_at, accounts.id").
+		Scan(&licences).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve licences"})
+		return
+	}
+
+	for i := range licences {
+		licences[i].Expired = licences[i].LicenceExpiresAt.Before(now)
+	}
+	if licences == nil {
+		licences = []expiringLicence{}
+	}
+
+	c.JSON(http.StatusOK, licences)
+}
+
+// findDoctor loads the account from the :id parameter and answers 404 if it
+// does not hold the doctor role.
+func findDoctor(c *gin.Context) (models.Account, bool) {
+	var doctor models.Account
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return doctor, false
+	}
+	if err := config.DB.Preload("Specializations").Preload("Roles").First(&doctor, id).Error; err != nil || !hasRole(doctor, "doctor") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+		return doctor, false
+	}
+	return doctor, true
+}
+
+// loadDoctorProfile returns the stored profile or an empty one for doctors
+// who have not filled it in yet.
+func loadDoctorProfile(accountID uint) (models.DoctorProfile, error) {
+	profile := models.DoctorProfile{AccountID: accountID}
+	err := config.DB.First(&profile, "account_id = ?", accountID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DoctorProfile{AccountID: accountID, Languages: []string{}}, nil
+	}
+	return profile, err
+}
+
+func newDoctorProfileResponse(profile models.DoctorProfile) doctorProfileResponse {
+	response := doctorProfileResponse{DoctorProfile: profile, YearsOfExperience: profile.YearsOfExperience(time.Now())}
+	if response.Languages == nil {
+		response.Languages = []string{}
+	}
+	if profile.HasPhoto {
+		response.PhotoURL = fmt.Sprintf("/api/Doctors/%d/Photo", profile.AccountID)
+	}
+	return response
+}
+
+func canManageDoctors(c *gin.Context) bool {
+	return utils.ContainsString(c.GetStringSlice("permissions"), models.PermissionDoctorWrite)
+}
+
+func canManageDoctorProfile(c *gin.Context, doctorID uint) bool {
+	return c.GetUint("account_id") == doctorID || canManageDoctors(c)
+}
+
+// canViewDoctorProfile hides profiles that are not public from everyone but
+// the doctor, doctor administrators and internal services.
+func canViewDoctorProfile(c *gin.Context, profile models.DoctorProfile) bool {
+	return profile.Public || c.GetBool("service") || canManageDoctorProfile(c, profile.AccountID)
+}
+
+func normalizeLanguages(languages []string) []string {
+	normalized := make([]string, 0, len(languages))
+	for _, language := range languages {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language != "" && !utils.ContainsString(normalized, language) {
+			normalized = append(normalized, language)
+		}
+	}
+	return normalized
+}
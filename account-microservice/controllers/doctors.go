@@ -1,20 +1,47 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
 	"account-microservice/config"
 	"account-microservice/models"
+	"account-microservice/utils"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// doctorSearchResult is the public view of a doctor in search results; it
+// leaves out the contact details and security state of the account.
+type doctorSearchResult struct {
+	ID              uint                     `json:"id"`
+	LastName        string                   `json:"lastName"`
+	FirstName       string                   `json:"firstName"`
+	Specializations []*models.Specialization `json:"specializations"`
+	NextFreeSlot    *time.Time               `json:"nextFreeSlot,omitempty"`
+	Hospitals       []utils.HospitalSummary  `json:"hospitals,omitempty"`
+}
+
+func newDoctorSearchResult(doctor models.Account) doctorSearchResult {
+	return doctorSearchResult{
+		ID:              doctor.ID,
+		LastName:        doctor.LastName,
+		FirstName:       doctor.FirstName,
+		Specializations: doctor.Specializations,
+	}
+}
+
+// GetDoctors lists doctors. Filters by hospital and free slots and the
+// "soonest" sort are resolved through timetable_service; in that case
+// pagination is applied after filtering.
 func GetDoctors(c *gin.Context) {
 	nameFilter := c.Query("nameFilter")
 	fromStr := c.Query("from")
 	countStr := c.Query("count")
+	sortBy := c.DefaultQuery("sort", "name")
 
 	var from, count int
 	var err error
@@ -35,18 +62,50 @@ func GetDoctors(c *gin.Context) {
 		}
 	}
 
+	if sortBy != "name" && sortBy != "soonest" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'sort' parameter, expected 'name' or 'soonest'"})
+		return
+	}
+
+	specializationID, ok := optionalUintQuery(c, "specializationId")
+	if !ok {
+		return
+	}
+	hospitalID, ok := optionalUintQuery(c, "hospitalId")
+	if !ok {
+		return
+	}
+	availableFrom, ok := optionalTimeQuery(c, "availableFrom")
+	if !ok {
+		return
+	}
+	availableTo, ok := optionalTimeQuery(c, "availableTo")
+	if !ok {
+		return
+	}
+	if availableFrom != nil && availableTo != nil && !availableTo.After(*availableFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'availableTo' must be after 'availableFrom'"})
+		return
+	}
+
 	var doctors []models.Account
-	query := config.DB.Preload("Specializations").Preload("Roles")
+	query := config.DB.Preload("Specializations")
 
 	query = query.Joins("JOIN account_roles ON accounts.id = account_roles.account_id").
 		Joins("JOIN roles ON roles.id = account_roles.role_id").
-		Where("roles.name = ?", "doctor")
+		Where("roles.name = ?", "doctor").
+		Order("accounts.last_name, accounts.first_name, accounts.id")
 
 	if nameFilter != "" {
 		query = query.Where("accounts.first_name ILIKE ? OR accounts.last_name ILIKE ?", "%"+nameFilter+"%", "%"+nameFilter+"%")
 	}
 
-	if count > 0 {
+	if specializationID != 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM doctor_specializations WHERE doctor_specializations.account_id = accounts.id AND doctor_specializations.specialization_id = ?)", specializationID)
+	}
+
+	needsTimetable := hospitalID != 0 || availableFrom != nil || availableTo != nil || sortBy == "soonest"
+	if count > 0 && !needsTimetable {
 		query = query.Offset(from).Limit(count)
 	}
 
@@ -55,60 +114,160 @@ func GetDoctors(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, doctors)
-}
+	results := make([]doctorSearchResult, 0, len(doctors))
+	if !needsTimetable {
+		for _, doctor := range doctors {
+			results = append(results, newDoctorSearchResult(doctor))
+		}
+		c.JSON(http.StatusOK, results)
+		return
+	}
 
-func GetDoctorByID(c *gin.Context) {
-	id := c.Param("id")
+	doctorIDs := make([]uint, 0, len(doctors))
+	for _, doctor := range doctors {
+		doctorIDs = append(doctorIDs, doctor.ID)
+	}
 
-	var doctor models.Account
-	if err := config.DB.Preload("Specializations").Preload("Roles").First(&doctor, id).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Doctor not found"})
+	availability, err := utils.FetchDoctorAvailability(utils.AvailabilityQuery{
+		DoctorIDs:  doctorIDs,
+		HospitalID: hospitalID,
+		From:       availableFrom,
+		To:         availableTo,
+	})
+	if err != nil {
+		log.Printf("Failed to fetch doctor availability: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to retrieve doctor availability"})
 		return
 	}
 
-	isDoctor := false
-	for _, role := range doctor.Roles {
-		if role.Name == "doctor" {
-			isDoctor = true
-			break
+	byDoctor := make(map[uint]utils.DoctorAvailability, len(availability))
+	for _, entry := range availability {
+		byDoctor[entry.DoctorID] = entry
+	}
+
+	windowed := availableFrom != nil || availableTo != nil
+	hospitalIDs := make(map[uint][]uint)
+	for _, doctor := range doctors {
+		entry, found := byDoctor[doctor.ID]
+		if (hospitalID != 0 && !found) || (windowed && entry.NextFreeSlot == nil) {
+			continue
+		}
+		result := newDoctorSearchResult(doctor)
+		result.NextFreeSlot = entry.NextFreeSlot
+		results = append(results, result)
+		hospitalIDs[doctor.ID] = entry.HospitalIDs
+	}
+
+	if sortBy == "soonest" {
+		sort.SliceStable(results, func(i, j int) bool {
+			a, b := results[i].NextFreeSlot, results[j].NextFreeSlot
+			if a == nil || b == nil {
+				return a != nil
+			}
+			return a.Before(*b)
+		})
+	}
+
+	if count > 0 {
+		if from > len(results) {
+			from = len(results)
 		}
+		results = results[from:min(from+count, len(results))]
 	}
 
-	if !isDoctor {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Account is not a doctor"})
+	if !attachHospitals(c, results, hospitalIDs) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":             doctor.ID,
-		"lastName":       doctor.LastName,
-		"firstName":      doctor.FirstName,
-		"specializations": doctor.Specializations,
-	})
+	c.JSON(http.StatusOK, results)
 }
 
-func CreateDoctor(c *gin.Context) {
-	rolesInterface, exists := c.Get("roles")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Roles not found"})
-		return
+// attachHospitals loads the hospitals of the returned doctors with one call
+// to hospital_service.
+func attachHospitals(c *gin.Context, results []doctorSearchResult, hospitalIDs map[uint][]uint) bool {
+	var ids []uint
+	for _, result := range results {
+		ids = append(ids, hospitalIDs[result.ID]...)
+	}
+
+	hospitals, err := utils.FetchHospitals(uniqueIDs(ids))
+	if err != nil {
+		log.Printf("Failed to fetch hospitals: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to retrieve hospitals"})
+		return false
 	}
 
-	roles := rolesInterface.([]interface{})
-	isAdmin := false
-	for _, role := range roles {
-		if role == "admin" {
-			isAdmin = true
-			break
+	byID := make(map[uint]utils.HospitalSummary, len(hospitals))
+	for _, hospital := range hospitals {
+		byID[hospital.ID] = hospital
+	}
+
+	for i := range results {
+		results[i].Hospitals = []utils.HospitalSummary{}
+		for _, id := range hospitalIDs[results[i].ID] {
+			if hospital, ok := byID[id]; ok {
+				results[i].Hospitals = append(results[i].Hospitals, hospital)
+			}
 		}
 	}
+	return true
+}
+
+func optionalUintQuery(c *gin.Context, name string) (uint, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || parsed == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid '" + name + "' parameter"})
+		return 0, false
+	}
+	return uint(parsed), true
+}
+
+func optionalTimeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid '" + name + "' parameter, expected RFC3339"})
+		return nil, false
+	}
+	return &parsed, true
+}
 
-	if !isAdmin {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin privileges required"})
+// GetDoctorByID returns the doctor with their profile. Profiles that are not
+// public are only included for the doctor, doctor administrators and
+// internal services.
+func GetDoctorByID(c *gin.Context) {
+	doctor, ok := findDoctor(c)
+	if !ok {
 		return
 	}
 
+	response := gin.H{
+		"id":              doctor.ID,
+		"lastName":        doctor.LastName,
+		"firstName":       doctor.FirstName,
+		"specializations": doctor.Specializations,
+	}
+
+	profile, err := loadDoctorProfile(doctor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve doctor profile"})
+		return
+	}
+	if canViewDoctorProfile(c, profile) {
+		response["profile"] = newDoctorProfileResponse(profile)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func CreateDoctor(c *gin.Context) {
 	var input struct {
 		LastName       string   `json:"lastName" binding:"required"`
 		FirstName      string   `json:"firstName" binding:"required"`
@@ -122,12 +281,6 @@ func CreateDoctor(c *gin.Context) {
 		return
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password encryption failed"})
-		return
-	}
-
 	var doctorRole models.Role
 	if err := config.DB.FirstOrCreate(&doctorRole, models.Role{Name: "doctor"}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve or create doctor role"})
@@ -137,7 +290,8 @@ func CreateDoctor(c *gin.Context) {
 	var specializations []*models.Specialization
 	for _, specName := range input.Specializations {
 		var specialization models.Specialization
-		if err := config.DB.FirstOrCreate(&specialization, models.Specialization{Name: specName}).Error; err != nil {
+		specName = normalizeSpecializationName(specName)
+		if err := config.DB.Where("LOWER(name) = LOWER(?)", specName).Attrs(models.Specialization{Name: specName}).FirstOrCreate(&specialization).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create specialization"})
 			return
 		}
@@ -148,15 +302,19 @@ func CreateDoctor(c *gin.Context) {
 		LastName:       input.LastName,
 		FirstName:      input.FirstName,
 		Username:       input.Username,
-		Password:       string(passwordHash),
+		MustChangePassword: true,
 		Roles:          []*models.Role{&doctorRole},
 		Specializations: specializations,
 	}
+	if !setPassword(c, &doctor, input.Password) {
+		return
+	}
 
-	if err := config.DB.Create(&doctor).Error; err != nil {
+	if err := createAccount(config.DB, &doctor); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
 		return
 	}
 
+	recordRoleChanges(c, doctor.ID, nil, doctor.Roles)
 	c.Status(http.StatusCreated)
 }
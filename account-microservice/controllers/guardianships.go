@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type guardianshipResponse struct {
+	models.Guardianship
+	Guardian  accountSummary `json:"guardian"`
+	Dependent accountSummary `json:"dependent"`
+	Active    bool           `json:"active"`
+}
+
+type accountSummary struct {
+	ID        uint   `json:"id"`
+	LastName  string `json:"lastName"`
+	FirstName string `json:"firstName"`
+}
+
+// RequestDependent asks another account to accept the current account as its
+// guardian. The link stays pending until the dependent consents. The response
+// is the same whether or not the username exists, and pending requests are
+// only shown to the dependent, so the endpoint cannot be used to look up
+// accounts.
+func RequestDependent(c *gin.Context) {
+	var input struct {
+		Username     string     `json:"username" binding:"required"`
+		Relationship string     `json:"relationship" binding:"required,max=64"`
+		ExpiresAt    *time.Time `json:"expiresAt"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'expiresAt' must be in the future"})
+		return
+	}
+
+	guardianID := c.GetUint("account_id")
+	var pending int64
+	if err := pendingGuardianships(config.DB.Where("guardian_id = ?", guardianID)).Count(&pending).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guardianship"})
+		return
+	}
+	if pending >= int64(config.GetInt("GUARDIANSHIP_MAX_PENDING", 5)) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many pending guardianship requests"})
+		return
+	}
+
+	var dependent models.Account
+	if err := config.DB.Where("username = ?", input.Username).First(&dependent).Error; err == nil {
+		_, err := insertGuardianship(c, guardianID, dependent.ID, input.Relationship, input.ExpiresAt, false)
+		if err != nil && !errors.Is(err, errOwnGuardian) && !errors.Is(err, errGuardianshipExists) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guardianship"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, it has been asked to accept the guardianship"})
+}
+
+// GetCurrentDependents leaves out pending requests, which would reveal
+// whether the requested username exists.
+func GetCurrentDependents(c *gin.Context) {
+	respondGuardianships(c, config.DB.Where("guardian_id = ? AND status = ?", c.GetUint("account_id"), models.GuardianshipActive))
+}
+
+func GetCurrentGuardians(c *gin.Context) {
+	respondGuardianships(c, config.DB.Where("dependent_id = ? AND status <> ?", c.GetUint("account_id"), models.GuardianshipRevoked))
+}
+
+// ConsentGuardian is called by the dependent to activate a pending link.
+func ConsentGuardian(c *gin.Context) {
+	guardianship, ok := findGuardianship(c, "dependent_id = ?", c.GetUint("account_id"))
+	if !ok {
+		return
+	}
+
+	if guardianship.Status != models.GuardianshipPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Guardianship is not pending"})
+		return
+	}
+	if guardianship.ExpiresAt != nil && !guardianship.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Guardianship has expired"})
+		return
+	}
+
+	now := time.Now()
+	guardianship.Status = models.GuardianshipActive
+	guardianship.ConsentedByID = c.GetUint("account_id")
+	guardianship.ConsentedAt = &now
+	if err := config.DB.Save(&guardianship).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update guardianship"})
+		return
+	}
+
+	recordSecurityEvent(c, guardianship.DependentID, models.SecurityEventGuardianConsented, fmt.Sprintf("guardian %d consented", guardianship.GuardianID))
+	c.JSON(http.StatusOK, newGuardianshipResponses([]models.Guardianship{guardianship})[0])
+}
+
+// RevokeGuardian lets the dependent withdraw consent.
+func RevokeGuardian(c *gin.Context) {
+	guardianship, ok := findGuardianship(c, "dependent_id = ?", c.GetUint("account_id"))
+	if !ok {
+		return
+	}
+	revokeGuardianship(c, guardianship)
+}
+
+// RemoveDependent lets the guardian give up a link. Pending requests are
+// hidden from the guardian, so only the dependent can decline them.
+func RemoveDependent(c *gin.Context) {
+	guardianship, ok := findGuardianship(c, "guardian_id = ? AND status <> ?", c.GetUint("account_id"), models.GuardianshipPending)
+	if !ok {
+		return
+	}
+	revokeGuardianship(c, guardianship)
+}
+
+func GetGuardianships(c *gin.Context) {
+	query := config.DB
+	if value := c.Query("accountId"); value != "" {
+		accountID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'accountId' parameter"})
+			return
+		}
+		query = query.Where("guardian_id = ? OR dependent_id = ?", accountID, accountID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	respondGuardianships(c, query)
+}
+
+// CreateGuardianship links two accounts on behalf of an administrator, for
+// instance a parent and a child without their own login. The administrator's
+// consent makes the link active immediately.
+func CreateGuardianship(c *gin.Context) {
+	var input struct {
+		GuardianID   uint       `json:"guardianId" binding:"required"`
+		DependentID  uint       `json:"dependentId" binding:"required"`
+		Relationship string     `json:"relationship" binding:"required,max=64"`
+		ExpiresAt    *time.Time `json:"expiresAt"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.Account{}).Where("id IN ?", []uint{input.GuardianID, input.DependentID}).Count(&count)
+	if count != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'expiresAt' must be in the future"})
+		return
+	}
+
+	guardianship, err := insertGuardianship(c, input.GuardianID, input.DependentID, input.Relationship, input.ExpiresAt, true)
+	switch {
+	case errors.Is(err, errOwnGuardian):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errGuardianshipExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guardianship"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newGuardianshipResponses([]models.Guardianship{guardianship})[0])
+}
+
+func DeleteGuardianship(c *gin.Context) {
+	guardianship, ok := findGuardianship(c, "")
+	if !ok {
+		return
+	}
+	revokeGuardianship(c, guardianship)
+}
+
+var (
+	errOwnGuardian        = errors.New("An account cannot be its own guardian")
+	errGuardianshipExists = errors.New("Guardianship already exists")
+)
+
+// pendingGuardianships narrows the query to requests still awaiting consent.
+func pendingGuardianships(query *gorm.DB) *gorm.DB {
+	return query.Model(&models.Guardianship{}).
+		Where("status = ?", models.GuardianshipPending).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
+func insertGuardianship(c *gin.Context, guardianID, dependentID uint, relationship string, expiresAt *time.Time, consented bool) (models.Guardianship, error) {
+	guardianship := models.Guardianship{
+		GuardianID:   guardianID,
+		DependentID:  dependentID,
+		Relationship: strings.TrimSpace(relationship),
+		Status:       models.GuardianshipPending,
+		ExpiresAt:    expiresAt,
+	}
+
+	if guardianID == dependentID {
+		return guardianship, errOwnGuardian
+	}
+
+	var count int64
+	err := config.DB.Model(&models.Guardianship{}).
+		Where("guardian_id = ? AND dependent_id = ? AND status <> ?", guardianID, dependentID, models.GuardianshipRevoked).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return guardianship, err
+	}
+	if count > 0 {
+		return guardianship, errGuardianshipExists
+	}
+
+	if consented {
+		now := time.Now()
+		guardianship.Status = models.GuardianshipActive
+		guardianship.ConsentedByID = c.GetUint("account_id")
+		guardianship.ConsentedAt = &now
+	}
+
+	if err := config.DB.Create(&guardianship).Error; err != nil {
+		return guardianship, err
+	}
+
+	eventType := models.SecurityEventGuardianRequested
+	if consented {
+		eventType = models.SecurityEventGuardianConsented
+	}
+	recordSecurityEvent(c, dependentID, eventType, fmt.Sprintf("guardian %d linked as %s", guardianID, guardianship.Relationship))
+	return guardianship, nil
+}
+
+// revokeGuardianship ends the link and revokes the guardian's access tokens,
+// which carry the dependent in their dependents claim.
+func revokeGuardianship(c *gin.Context, guardianship models.Guardianship) {
+	if guardianship.Status == models.GuardianshipRevoked {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	wasActive := guardianship.Status == models.GuardianshipActive
+	now := time.Now()
+	guardianship.Status = models.GuardianshipRevoked
+	guardianship.RevokedAt = &now
+	if err := config.DB.Save(&guardianship).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke guardianship"})
+		return
+	}
+
+	if wasActive {
+		if err := services.RevokeAccount(guardianship.GuardianID, "guardianship revoked"); err != nil {
+			log.Printf("Failed to revoke tokens of guardian %d: %v", guardianship.GuardianID, err)
+		}
+	}
+
+	recordSecurityEvent(c, guardianship.DependentID, models.SecurityEventGuardianRevoked, fmt.Sprintf("guardian %d unlinked", guardianship.GuardianID))
+	c.Status(http.StatusOK)
+}
+
+func findGuardianship(c *gin.Context, scope string, args ...interface{}) (models.Guardianship, bool) {
+	var guardianship models.Guardianship
+	id, err := strconv.ParseUint(c.Param("linkId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid guardianship ID"})
+		return guardianship, false
+	}
+	query := config.DB
+	if scope != "" {
+		query = query.Where(scope, args...)
+	}
+	if err := query.First(&guardianship, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Guardianship not found"})
+		return guardianship, false
+	}
+	return guardianship, true
+}
+
+func respondGuardianships(c *gin.Context, query *gorm.DB) {
+	var guardianships []models.Guardianship
+	if err := query.Order("created_at DESC, id DESC").Find(&guardianships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve guardianships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newGuardianshipResponses(guardianships))
+}
+
+func newGuardianshipResponses(guardianships []models.Guardianship) []guardianshipResponse {
+	var ids []uint
+	for _, guardianship := range guardianships {
+		ids = append(ids, guardianship.GuardianID, guardianship.DependentID)
+	}
+
+	summaries := make(map[uint]accountSummary)
+	var accounts []models.Account
+	if len(ids) > 0 {
+		config.DB.Unscoped().Where("id IN ?", uniqueIDs(ids)).Find(&accounts)
+	}
+	for _, account := range accounts {
+		summaries[account.ID] = accountSummary{ID: account.ID, LastName: account.LastName, FirstName: account.FirstName}
+	}
+
+	now := time.Now()
+	responses := make([]guardianshipResponse, 0, len(guardianships))
+	for _, guardianship := range guardianships {
+		responses = append(responses, guardianshipResponse{
+			Guardianship: guardianship,
+			Guardian:     summaries[guardianship.GuardianID],
+			Dependent:    summaries[guardianship.DependentID],
+			Active:       guardianship.IsActive(now),
+		})
+	}
+	return responses
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func GetCurrentHospitals(c *gin.Context) {
+	respondHospitalAccess(c, c.GetUint("account_id"))
+}
+
+func GetAccountHospitals(c *gin.Context) {
+	accountID, ok := findAccountID(c)
+	if !ok {
+		return
+	}
+	respondHospitalAccess(c, accountID)
+}
+
+// UpdateAccountHospitals replaces the hospitals an account is affiliated
+// with. Every affiliation needs a hospital-scoped role.
+func UpdateAccountHospitals(c *gin.Context) {
+	accountID, ok := findAccountID(c)
+	if !ok {
+		return
+	}
+
+	var input []struct {
+		HospitalID uint   `json:"hospitalId" binding:"required"`
+		Role       string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	affiliations := make([]models.HospitalAffiliation, 0, len(input))
+	hospitalIDs := make([]uint, 0, len(input))
+	seen := make(map[uint]bool)
+	for _, item := range input {
+		if seen[item.HospitalID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Hospital %d is listed more than once", item.HospitalID)})
+			return
+		}
+		seen[item.HospitalID] = true
+
+		var role models.Role
+		if err := config.DB.Where("name = ?", models.NormalizeRoleName(item.Role)).First(&role).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Role %s not found", item.Role)})
+			return
+		}
+		if !role.HospitalScoped {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Role %s is not hospital-scoped", role.Name)})
+			return
+		}
+
+		affiliations = append(affiliations, models.HospitalAffiliation{AccountID: accountID, HospitalID: item.HospitalID, RoleID: role.ID, Role: &role})
+		hospitalIDs = append(hospitalIDs, item.HospitalID)
+	}
+
+	if len(hospitalIDs) > 0 {
+		hospitals, err := utils.FetchHospitals(hospitalIDs)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to retrieve hospitals"})
+			return
+		}
+		found := make(map[uint]bool, len(hospitals))
+		for _, hospital := range hospitals {
+			found[hospital.ID] = true
+		}
+		for _, id := range hospitalIDs {
+			if !found[id] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Hospital %d not found", id)})
+				return
+			}
+		}
+	}
+
+	var previous []models.HospitalAffiliation
+	if err := config.DB.Preload("Role").Where("account_id = ?", accountID).Find(&previous).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hospital affiliations"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", accountID).Delete(&models.HospitalAffiliation{}).Error; err != nil {
+			return err
+		}
+		if len(affiliations) == 0 {
+			return nil
+		}
+		return tx.Omit("Role").Create(&affiliations).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update hospital affiliations"})
+		return
+	}
+
+	recordAffiliationChanges(c, accountID, previous, affiliations)
+	if err := services.RevokeAccount(accountID, "hospital affiliations changed"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke existing tokens"})
+		return
+	}
+
+	respondHospitalAccess(c, accountID)
+}
+
+func respondHospitalAccess(c *gin.Context, accountID uint) {
+	access, err := services.HospitalAccess(accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hospital affiliations"})
+		return
+	}
+	c.JSON(http.StatusOK, access)
+}
+
+// recordAffiliationChanges logs affiliation changes as role assignments so
+// that they show up in the role audit.
+func recordAffiliationChanges(c *gin.Context, accountID uint, before, after []models.HospitalAffiliation) {
+	had := make(map[uint]string)
+	for _, affiliation := range before {
+		if affiliation.Role != nil {
+			had[affiliation.HospitalID] = affiliation.Role.Name
+		}
+	}
+	has := make(map[uint]string)
+	for _, affiliation := range after {
+		has[affiliation.HospitalID] = affiliation.Role.Name
+		if had[affiliation.HospitalID] != affiliation.Role.Name {
+			recordSecurityEvent(c, accountID, models.SecurityEventRoleAssigned, fmt.Sprintf("role %s assigned in hospital %d", affiliation.Role.Name, affiliation.HospitalID))
+		}
+	}
+	for hospitalID, role := range had {
+		if has[hospitalID] != role {
+			recordSecurityEvent(c, accountID, models.SecurityEventRoleRemoved, fmt.Sprintf("role %s removed in hospital %d", role, hospitalID))
+		}
+	}
+}
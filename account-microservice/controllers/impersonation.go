@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImpersonationMinutes = 60
+
+// ImpersonateAccount issues a short-lived access token for another account.
+// The token names the administrator in its act claim, is read-only unless
+// asked otherwise and cannot be refreshed.
+func ImpersonateAccount(c *gin.Context) {
+	var input struct {
+		Reason   string `json:"reason" binding:"required,max=512"`
+		ReadOnly *bool  `json:"readOnly"`
+		Minutes  int    `json:"minutes" binding:"omitempty,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.GetUint("impersonator_id") != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Impersonation tokens cannot start another impersonation"})
+		return
+	}
+
+	accountID, ok := findAccountID(c)
+	if !ok {
+		return
+	}
+	adminID := c.GetUint("account_id")
+	if accountID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot impersonate yourself"})
+		return
+	}
+
+	var admin, account models.Account
+	if err := config.DB.First(&admin, adminID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account not found"})
+		return
+	}
+	if err := config.DB.Preload("Roles").First(&account, accountID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if err := services.CheckAccountStatus(account); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := accessClaims(account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	if utils.ContainsString(claims.Permissions, models.PermissionAccountImpersonate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Accounts that can impersonate others cannot be impersonated"})
+		return
+	}
+
+	minutes := 15
+	if input.Minutes != 0 {
+		minutes = min(input.Minutes, maxImpersonationMinutes)
+	}
+	readOnly := input.ReadOnly == nil || *input.ReadOnly
+
+	claims.Actor = &utils.Actor{AccountID: admin.ID, Username: admin.Username}
+	claims.ReadOnly = readOnly
+	claims.Lifetime = time.Duration(minutes) * time.Minute
+	expiresAt := time.Now().Add(claims.Lifetime)
+
+	accessToken, err := utils.GenerateAccessToken(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	recordSecurityEvent(c, account.ID, models.SecurityEventImpersonation,
+		fmt.Sprintf("impersonated by %s for %d minutes, read-only %t: %s", admin.Username, minutes, readOnly, strings.TrimSpace(input.Reason)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"accessToken": accessToken,
+		"expiresAt":   expiresAt,
+		"readOnly":    readOnly,
+	})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AcceptInvite sets the first password of an imported account.
+func AcceptInvite(c *gin.Context) {
+	var input struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"newPassword" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invite models.InviteToken
+	if err := config.DB.
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(strings.TrimSpace(input.Token)), time.Now()).
+		First(&invite).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invite token"})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, invite.AccountID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invite token"})
+		return
+	}
+
+	if !setPassword(c, &account, input.NewPassword) {
+		return
+	}
+	account.MustChangePassword = false
+	// The invite was delivered to the account's email address.
+	if account.Email != "" {
+		account.EmailVerified = true
+	}
+
+	var used bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&invite).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			used = true
+			return nil
+		}
+		return saveAccountPassword(tx, &account)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invite"})
+		return
+	}
+	if used {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invite token"})
+		return
+	}
+
+	recordSecurityEvent(c, account.ID, models.SecurityEventInviteAccepted, "invite accepted")
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been set"})
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetSigningKeys(c *gin.Context) {
+	var keys []models.SigningKey
+	if err := config.DB.Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve signing keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func RotateSigningKeys(c *gin.Context) {
+	var input struct {
+		Uses []string `json:"uses"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(input.Uses) == 0 {
+		input.Uses = []string{utils.KeyUseAccess, utils.KeyUseRefresh}
+	}
+
+	var keyrings []*utils.Keyring
+	for _, use := range input.Uses {
+		keyring, err := utils.KeyringFor(use)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		keyrings = append(keyrings, keyring)
+	}
+
+	var rotated []*models.SigningKey
+	for _, keyring := range keyrings {
+		key, err := keyring.Rotate()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate " + keyring.Use() + " key"})
+			return
+		}
+		rotated = append(rotated, key)
+	}
+
+	c.JSON(http.StatusOK, rotated)
+}
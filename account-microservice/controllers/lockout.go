@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAccountLockout(c *gin.Context) {
+	account, ok := findLockoutAccount(c)
+	if !ok {
+		return
+	}
+
+	attempt, err := services.GetLoginAttempt(services.UserLockoutKey(account.Username))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockout state"})
+		return
+	}
+
+	response := gin.H{
+		"accountId": account.ID,
+		"username":  account.Username,
+		"failures":  0,
+		"locked":    false,
+	}
+	if attempt != nil {
+		response["failures"] = attempt.Failures
+		response["lastFailureAt"] = attempt.LastFailureAt
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(time.Now()) {
+			response["locked"] = true
+			response["lockedUntil"] = attempt.LockedUntil
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func UnlockAccount(c *gin.Context) {
+	account, ok := findLockoutAccount(c)
+	if !ok {
+		return
+	}
+
+	if err := services.ClearLoginFailures(services.UserLockoutKey(account.Username)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	recordSecurityEvent(c, account.ID, models.SecurityEventAccountUnlocked, "unlocked by account "+strconv.FormatUint(uint64(c.GetUint("account_id")), 10))
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+func GetLockouts(c *gin.Context) {
+	lockouts, err := services.ActiveLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve lockouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+func findLockoutAccount(c *gin.Context) (models.Account, bool) {
+	var account models.Account
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return account, false
+	}
+
+	if err := config.DB.First(&account, accountID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return account, false
+	}
+	return account, true
+}
+
+func recordLoginFailure(c *gin.Context, accountID uint, userKey, ipKey string) {
+	locked, err := services.RecordLoginFailure(userKey, ipKey)
+	if err != nil {
+		log.Printf("Failed to record sign-in failure for %s: %v", userKey, err)
+		return
+	}
+
+	for _, key := range locked {
+		if strings.HasPrefix(key, "user:") && accountID == 0 {
+			continue
+		}
+		recordSecurityEvent(c, accountID, models.SecurityEventAccountLocked, key+" locked after repeated sign-in failures")
+	}
+}
+
+// checkMFAAttempt refuses a second-factor code while the account or address
+// is throttled, or once the challenge has used up its attempts.
+func checkMFAAttempt(c *gin.Context, account models.Account, jti string) *signInError {
+	retryAfter, err := services.LoginRetryAfter(services.UserLockoutKey(account.Username), services.IPLockoutKey(c.ClientIP()))
+	if err != nil {
+		return &signInError{status: http.StatusInternalServerError, message: "Failed to check sign-in attempts"}
+	}
+	if retryAfter > 0 {
+		return &signInError{status: http.StatusTooManyRequests, message: "Too many failed sign-in attempts, try again later", retryAfter: retryAfter}
+	}
+
+	if jti == "" {
+		return nil
+	}
+	exhausted, err := services.ChallengeExhausted(jti)
+	if err != nil {
+		return &signInError{status: http.StatusInternalServerError, message: "Failed to check sign-in attempts"}
+	}
+	if exhausted {
+		return &signInError{status: http.StatusUnauthorized, message: "Too many invalid codes, sign in again"}
+	}
+	return nil
+}
+
+// recordMFAFailure counts an invalid second-factor code like a failed
+// password, and against the challenge it was entered for. jti is empty when
+// the code is entered together with the password.
+func recordMFAFailure(c *gin.Context, account models.Account, jti string) {
+	recordLoginFailure(c, account.ID, services.UserLockoutKey(account.Username), services.IPLockoutKey(c.ClientIP()))
+	auditSignInFailure(c, account.ID, account.Username, "invalid two-factor code")
+
+	if jti == "" {
+		return
+	}
+	if _, err := services.RecordChallengeFailure(jti); err != nil {
+		log.Printf("Failed to record invalid code for challenge %s: %v", jti, err)
+	}
+}
+
+func clearMFAFailures(account models.Account) {
+	key := services.UserLockoutKey(account.Username)
+	if err := services.ClearLoginFailures(key); err != nil {
+		log.Printf("Failed to clear sign-in failures for %s: %v", key, err)
+	}
+}
+
+func respondTooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "Too many failed sign-in attempts, try again later",
+		"retryAfter": seconds,
+	})
+}
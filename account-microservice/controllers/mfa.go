@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+func EnrollMFAChallenge(c *gin.Context) {
+	var input struct {
+		Challenge string `json:"challenge" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(input.Challenge, utils.ChallengeMFAEnrollment)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, uint(claims["account_id"].(float64))).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if account.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	beginTOTPEnrollment(c, &account)
+}
+
+func VerifyMFAChallenge(c *gin.Context) {
+	var input struct {
+		Challenge    string `json:"challenge" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(input.Challenge, utils.ChallengeMFA, utils.ChallengeMFAEnrollment)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.Preload("Roles").First(&account, uint(claims["account_id"].(float64))).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account not found"})
+		return
+	}
+
+	jti, _ := claims["jti"].(string)
+	if attemptErr := checkMFAAttempt(c, account, jti); attemptErr != nil {
+		respondSignInError(c, attemptErr)
+		return
+	}
+
+	var recoveryCodes []string
+	switch {
+	case !account.TOTPEnabled:
+		if account.TOTPSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor enrollment has not been started"})
+			return
+		}
+		if !verifyTOTP(&account, input.Code) {
+			recordMFAFailure(c, account, jti)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return
+		}
+		recoveryCodes, err = enableTOTP(&account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+			return
+		}
+	case input.RecoveryCode != "":
+		if !useRecoveryCode(account.ID, input.RecoveryCode) {
+			recordMFAFailure(c, account, jti)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid recovery code"})
+			return
+		}
+	default:
+		if !verifyTOTP(&account, input.Code) {
+			recordMFAFailure(c, account, jti)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return
+		}
+	}
+	clearMFAFailures(account)
+
+	deviceName, _ := claims["device"].(string)
+	var extra gin.H
+	if recoveryCodes != nil {
+		extra = gin.H{"recoveryCodes": recoveryCodes}
+	}
+
+	completeSignIn(c, account, deviceName, extra)
+}
+
+func BeginTOTPEnrollment(c *gin.Context) {
+	var account models.Account
+	if err := config.DB.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if account.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	beginTOTPEnrollment(c, &account)
+}
+
+func ConfirmTOTPEnrollment(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if account.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if account.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor enrollment has not been started"})
+		return
+	}
+
+	if !verifyTOTP(&account, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	recoveryCodes, err := enableTOTP(&account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+func DisableTOTP(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.Preload("Roles").First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if !account.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if requiresMFA(account) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+
+	if !verifyTOTP(&account, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	if err := resetTOTP(account.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if !account.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if !verifyTOTP(&account, input.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	recoveryCodes, err := generateRecoveryCodes(config.DB, account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+func ResetAccountTOTP(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	if err := resetTOTP(uint(accountID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func requiresMFA(account models.Account) bool {
+	for _, role := range account.Roles {
+		if role.MFARequired {
+			return true
+		}
+	}
+	return false
+}
+
+func respondWithMFAChallenge(c *gin.Context, account models.Account, deviceName string) {
+	purpose := utils.ChallengeMFA
+	if !account.TOTPEnabled {
+		purpose = utils.ChallengeMFAEnrollment
+	}
+
+	challenge, err := utils.GenerateChallengeToken(account.ID, purpose, deviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mfaRequired":           account.TOTPEnabled,
+		"mfaEnrollmentRequired": !account.TOTPEnabled,
+		"challenge":             challenge,
+		"expiresIn":             int(utils.ChallengeLifetime.Seconds()),
+	})
+}
+
+func beginTOTPEnrollment(c *gin.Context, account *models.Account) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate secret"})
+		return
+	}
+
+	if err := config.DB.Model(account).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Volga"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":          secret,
+		"provisioningUri": utils.TOTPProvisioningURI(issuer, account.Username, secret),
+	})
+}
+
+func verifyTOTP(account *models.Account, code string) bool {
+	step, ok := utils.ValidateTOTP(account.TOTPSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return false
+	}
+
+	result := config.DB.Model(&models.Account{}).
+		Where("id = ? AND totp_last_step < ?", account.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	account.TOTPLastStep = step
+	return true
+}
+
+func enableTOTP(account *models.Account) ([]string, error) {
+	var recoveryCodes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		recoveryCodes, err = generateRecoveryCodes(tx, account.ID)
+		return err
+	})
+	return recoveryCodes, err
+}
+
+func resetTOTP(accountID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Account{}).Where("id = ?", accountID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("account_id = ?", accountID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+func generateRecoveryCodes(db *gorm.DB, accountID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomString(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{AccountID: accountID, CodeHash: utils.HashToken(code)})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", accountID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func useRecoveryCode(accountID uint, code string) bool {
+	hash := utils.HashToken(strings.ToLower(strings.TrimSpace(code)))
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("account_id = ? AND code_hash = ? AND used_at IS NULL", accountID, hash).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
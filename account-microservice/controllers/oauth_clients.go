@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var supportedGrantTypes = []string{"authorization_code", "refresh_token", "client_credentials"}
+
+func GetOAuthClients(c *gin.Context) {
+	var clients []models.OAuthClient
+	if err := config.DB.Order("created_at").Find(&clients).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve clients"})
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+func CreateOAuthClient(c *gin.Context) {
+	var input struct {
+		Name         string   `json:"name" binding:"required"`
+		RedirectURIs []string `json:"redirectUris"`
+		Scopes       []string `json:"scopes"`
+		GrantTypes   []string `json:"grantTypes"`
+		Public       bool     `json:"public"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	grantTypes := input.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = models.DefaultGrantTypes
+	}
+	for _, grantType := range grantTypes {
+		if !utils.ContainsString(supportedGrantTypes, grantType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported grant type: " + grantType})
+			return
+		}
+	}
+	serviceClient := utils.ContainsString(grantTypes, "client_credentials")
+	if serviceClient && input.Public {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Public clients cannot use the client_credentials grant"})
+		return
+	}
+	if utils.ContainsString(grantTypes, "authorization_code") && len(input.RedirectURIs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one redirect URI is required"})
+		return
+	}
+
+	for _, redirectURI := range input.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect URI: " + redirectURI})
+			return
+		}
+	}
+
+	scopes := input.Scopes
+	if len(scopes) == 0 && !serviceClient {
+		scopes = []string{"openid", "profile"}
+	}
+	for _, scope := range scopes {
+		supported := utils.ContainsString(utils.SupportedScopes, scope) ||
+			serviceClient && utils.ContainsString(utils.ServiceScopes, scope)
+		if !supported {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported scope: " + scope})
+			return
+		}
+	}
+
+	clientID, err := utils.RandomString(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate client ID"})
+		return
+	}
+
+	client := models.OAuthClient{
+		ClientID:     clientID,
+		Name:         input.Name,
+		RedirectURIs: input.RedirectURIs,
+		Scopes:       scopes,
+		GrantTypes:   grantTypes,
+		Public:       input.Public,
+	}
+
+	var secret string
+	if !input.Public {
+		secret, err = utils.RandomString(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate client secret"})
+			return
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	if err := config.DB.Create(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register client"})
+		return
+	}
+
+	response := gin.H{
+		"clientId":     client.ClientID,
+		"name":         client.Name,
+		"redirectUris": client.RedirectURIs,
+		"scopes":       client.Scopes,
+		"grantTypes":   client.GrantTypes,
+		"public":       client.Public,
+	}
+	if secret != "" {
+		response["clientSecret"] = secret
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func DeleteOAuthClient(c *gin.Context) {
+	clientID := c.Param("clientId")
+
+	var client models.OAuthClient
+	if err := config.DB.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", clientID).Delete(&models.OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", clientID).Delete(&models.AuthorizationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&client).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete client"})
+		return
+	}
+
+	if err := services.RevokeClient(clientID, "client deleted"); err != nil {
+		log.Printf("Failed to revoke tokens of deleted client %s: %v", clientID, err)
+	}
+
+	var sessions []models.Session
+	config.DB.Where("client_id = ? AND revoked_at IS NULL", clientID).Find(&sessions)
+	for i := range sessions {
+		if err := revokeSession(&sessions[i]); err != nil {
+			log.Printf("Failed to revoke session %d of deleted client %s: %v", sessions[i].ID, clientID, err)
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func validRedirectURI(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Fragment != "" {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return parsed.Host != ""
+	case "http":
+		host := parsed.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		return strings.Contains(parsed.Scheme, ".")
+	}
+}
@@ -0,0 +1,638 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+const ssoCookieName = "volga_sso"
+
+type authorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Prompt              string
+
+	client models.OAuthClient
+	scopes []string
+	// redirectURI is RedirectURI, or the client's only registered URI when
+	// the request omitted it.
+	redirectURI string
+}
+
+type authorizationError struct {
+	code        string
+	description string
+	redirect    bool
+}
+
+func GetOpenIDConfiguration(c *gin.Context) {
+	issuer := utils.Issuer()
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{utils.AccessKeys.Algorithm()},
+		"scopes_supported":                      utils.SupportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"prompt_values_supported":               []string{"none", "login", "consent"},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "sid",
+			"name", "given_name", "family_name", "preferred_username",
+			"email", "email_verified", "phone_number", "phone_number_verified",
+		},
+	})
+}
+
+func Authorize(c *gin.Context) {
+	request := readAuthorizationRequest(c)
+	if authErr := request.validate(); authErr != nil {
+		respondAuthorizationError(c, request, authErr)
+		return
+	}
+
+	account, authTime, ok := ssoAccount(c)
+	if !ok || request.hasPrompt("login") {
+		if request.hasPrompt("none") {
+			respondAuthorizationError(c, request, &authorizationError{code: "login_required", redirect: true})
+			return
+		}
+		renderLoginPage(c, http.StatusOK, request, "")
+		return
+	}
+
+	continueAuthorization(c, request, account, authTime)
+}
+
+func AuthorizeSubmit(c *gin.Context) {
+	request := readAuthorizationRequest(c)
+	if authErr := request.validate(); authErr != nil {
+		respondAuthorizationError(c, request, authErr)
+		return
+	}
+
+	if ticket := c.PostForm("ticket"); ticket != "" {
+		submitConsent(c, request, ticket)
+		return
+	}
+
+	account, signInErr := checkCredentials(c, c.PostForm("username"), c.PostForm("password"))
+	if signInErr != nil {
+		renderLoginPage(c, signInErr.status, request, signInErr.message)
+		return
+	}
+
+	if account.TOTPEnabled {
+		if strings.TrimSpace(c.PostForm("code")) == "" {
+			renderLoginPage(c, http.StatusUnauthorized, request, "Enter the code from your authenticator app")
+			return
+		}
+		if !verifyTOTP(&account, c.PostForm("code")) {
+			recordMFAFailure(c, account, "")
+			renderLoginPage(c, http.StatusUnauthorized, request, "Invalid two-factor code")
+			return
+		}
+		clearMFAFailures(account)
+	} else if requiresMFA(account) {
+		renderLoginPage(c, http.StatusForbidden, request, "Your role requires two-factor authentication, complete enrollment in the application first")
+		return
+	}
+
+	if account.MustChangePassword {
+		renderLoginPage(c, http.StatusForbidden, request, "You must change your initial password before signing in here")
+		return
+	}
+
+	authTime := time.Now()
+	ssoToken, err := utils.GenerateSSOToken(account.ID, authTime)
+	if err != nil {
+		renderLoginPage(c, http.StatusInternalServerError, request, "Could not start session")
+		return
+	}
+	secure := strings.HasPrefix(utils.Issuer(), "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoCookieName, ssoToken, int(utils.SSOLifetime.Seconds()), "/oauth", "", secure, true)
+
+	continueAuthorization(c, request, account, authTime)
+}
+
+func Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := authenticateClient(c)
+	if !ok {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client", "error_description": "Client authentication failed"})
+		return
+	}
+
+	grantType := c.PostForm("grant_type")
+	switch grantType {
+	case "authorization_code", "refresh_token", "client_credentials":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	if !client.AllowsGrant(grantType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unauthorized_client", "error_description": "Grant type " + grantType + " is not allowed for this client"})
+		return
+	}
+
+	switch grantType {
+	case "authorization_code":
+		exchangeAuthorizationCode(c, client)
+	case "refresh_token":
+		exchangeRefreshToken(c, client)
+	case "client_credentials":
+		issueServiceToken(c, client)
+	}
+}
+
+func UserInfo(c *gin.Context) {
+	var account models.Account
+	if err := config.DB.First(&account, c.GetUint("account_id")).Error; err != nil || services.CheckAccountStatus(account) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	scopes := utils.SupportedScopes
+	claims := c.MustGet("claims").(jwt.MapClaims)
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = strings.Fields(scope)
+		if !utils.ContainsString(scopes, "openid") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+			return
+		}
+	}
+
+	response := profileClaims(account, scopes)
+	response["sub"] = strconv.FormatUint(uint64(account.ID), 10)
+	c.JSON(http.StatusOK, response)
+}
+
+func readAuthorizationRequest(c *gin.Context) authorizationRequest {
+	value := c.Query
+	if c.Request.Method == http.MethodPost {
+		value = c.PostForm
+	}
+
+	return authorizationRequest{
+		ClientID:            value("client_id"),
+		RedirectURI:         value("redirect_uri"),
+		ResponseType:        value("response_type"),
+		Scope:               value("scope"),
+		State:               value("state"),
+		Nonce:               value("nonce"),
+		CodeChallenge:       value("code_challenge"),
+		CodeChallengeMethod: value("code_challenge_method"),
+		Prompt:              value("prompt"),
+	}
+}
+
+func (r *authorizationRequest) validate() *authorizationError {
+	if r.ClientID == "" {
+		return &authorizationError{code: "invalid_request", description: "client_id is required"}
+	}
+	if err := config.DB.Where("client_id = ?", r.ClientID).First(&r.client).Error; err != nil {
+		return &authorizationError{code: "unauthorized_client", description: "Unknown client"}
+	}
+
+	if !r.client.AllowsGrant("authorization_code") {
+		return &authorizationError{code: "unauthorized_client", description: "This client cannot use the authorization code flow"}
+	}
+
+	r.redirectURI = r.RedirectURI
+	if r.redirectURI == "" && len(r.client.RedirectURIs) == 1 {
+		r.redirectURI = r.client.RedirectURIs[0]
+	}
+	if !utils.ContainsString(r.client.RedirectURIs, r.redirectURI) {
+		return &authorizationError{code: "invalid_request", description: "redirect_uri is not registered for this client"}
+	}
+
+	if r.ResponseType != "code" {
+		return &authorizationError{code: "unsupported_response_type", description: "Only the code response type is supported", redirect: true}
+	}
+
+	r.scopes = strings.Fields(r.Scope)
+	if !utils.ContainsString(r.scopes, "openid") {
+		return &authorizationError{code: "invalid_scope", description: "The openid scope is required", redirect: true}
+	}
+	for _, scope := range r.scopes {
+		if !utils.ContainsString(r.client.Scopes, scope) {
+			return &authorizationError{code: "invalid_scope", description: "Scope " + scope + " is not allowed for this client", redirect: true}
+		}
+	}
+
+	if r.CodeChallenge == "" || r.CodeChallengeMethod != "S256" {
+		return &authorizationError{code: "invalid_request", description: "PKCE with the S256 method is required", redirect: true}
+	}
+
+	return nil
+}
+
+func (r authorizationRequest) hasPrompt(prompt string) bool {
+	return utils.ContainsString(strings.Fields(r.Prompt), prompt)
+}
+
+// fingerprint identifies the request a consent ticket was issued for.
+func (r authorizationRequest) fingerprint() string {
+	return utils.HashToken(strings.Join([]string{
+		r.ClientID, r.RedirectURI, r.Scope, r.State, r.Nonce, r.CodeChallenge,
+	}, "\n"))
+}
+
+func continueAuthorization(c *gin.Context, request authorizationRequest, account models.Account, authTime time.Time) {
+	if !request.hasPrompt("consent") && hasConsent(account.ID, request.ClientID, request.scopes) {
+		issueAuthorizationCode(c, request, account, authTime)
+		return
+	}
+
+	if request.hasPrompt("none") {
+		respondAuthorizationError(c, request, &authorizationError{code: "consent_required", redirect: true})
+		return
+	}
+
+	ticket, err := utils.GenerateConsentTicket(account.ID, request.fingerprint())
+	if err != nil {
+		respondAuthorizationError(c, request, &authorizationError{code: "server_error", redirect: true})
+		return
+	}
+
+	renderConsentPage(c, request, account, ticket)
+}
+
+func submitConsent(c *gin.Context, request authorizationRequest, ticket string) {
+	account, authTime, ok := ssoAccount(c)
+	if !ok {
+		renderLoginPage(c, http.StatusUnauthorized, request, "Your session has expired, sign in again")
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(ticket, utils.ChallengeOIDCConsent)
+	if err != nil || uint(claims["account_id"].(float64)) != account.ID || claims["request"] != request.fingerprint() {
+		renderLoginPage(c, http.StatusBadRequest, request, "The consent form has expired, sign in again")
+		return
+	}
+
+	if c.PostForm("decision") != "approve" {
+		respondAuthorizationError(c, request, &authorizationError{code: "access_denied", description: "The user denied access", redirect: true})
+		return
+	}
+
+	if err := saveConsent(account.ID, request.ClientID, request.scopes); err != nil {
+		respondAuthorizationError(c, request, &authorizationError{code: "server_error", redirect: true})
+		return
+	}
+
+	issueAuthorizationCode(c, request, account, authTime)
+}
+
+func issueAuthorizationCode(c *gin.Context, request authorizationRequest, account models.Account, authTime time.Time) {
+	code, err := utils.RandomString(32)
+	if err != nil {
+		respondAuthorizationError(c, request, &authorizationError{code: "server_error", redirect: true})
+		return
+	}
+
+	authorizationCode := models.AuthorizationCode{
+		CodeHash:            utils.HashToken(code),
+		ClientID:            request.ClientID,
+		AccountID:           account.ID,
+		RedirectURI:         request.redirectURI,
+		RedirectURIProvided: request.RedirectURI != "",
+		Scope:               strings.Join(request.scopes, " "),
+		Nonce:               request.Nonce,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		AuthTime:            authTime,
+		ExpiresAt:           time.Now().Add(utils.AuthorizationCodeLifetime),
+	}
+	if err := config.DB.Create(&authorizationCode).Error; err != nil {
+		respondAuthorizationError(c, request, &authorizationError{code: "server_error", redirect: true})
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	redirectWithParams(c, request.redirectURI, params)
+}
+
+func exchangeAuthorizationCode(c *gin.Context, client models.OAuthClient) {
+	var code models.AuthorizationCode
+	if err := config.DB.
+		Where("code_hash = ? AND client_id = ? AND expires_at > ?", utils.HashToken(c.PostForm("code")), client.ClientID, time.Now()).
+		First(&code).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "Invalid or expired authorization code"})
+		return
+	}
+
+	if code.UsedAt != nil {
+		revokeAuthorizationCodeSession(code)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "Authorization code has already been used"})
+		return
+	}
+
+	// RFC 6749 section 4.1.3: redirect_uri must match only when the
+	// authorization request included it.
+	if code.RedirectURIProvided && c.PostForm("redirect_uri") != code.RedirectURI {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "redirect_uri does not match"})
+		return
+	}
+
+	if !utils.VerifyPKCE(c.PostForm("code_verifier"), code.CodeChallenge) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "Invalid code_verifier"})
+		return
+	}
+
+	result := config.DB.Model(&models.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", code.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "Authorization code has already been used"})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.Preload("Roles").First(&account, code.AccountID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": "Account not found"})
+		return
+	}
+	if err := services.CheckAccountStatus(account); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": err.Error()})
+		return
+	}
+
+	session, refreshToken, err := startClientSession(c, account, client.Name, client.ClientID, code.Scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	config.DB.Model(&code).Update("session_id", session.ID)
+
+	response, err := oidcTokenResponse(account, session, code.Nonce, code.AuthTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	if utils.ContainsString(strings.Fields(code.Scope), "offline_access") {
+		response["refresh_token"] = refreshToken
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func exchangeRefreshToken(c *gin.Context, client models.OAuthClient) {
+	tokens, tokenErr := rotateRefreshToken(c, c.PostForm("refresh_token"), client.ClientID)
+	if tokenErr != nil {
+		if tokenErr.status >= http.StatusInternalServerError {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant", "error_description": tokenErr.message})
+		return
+	}
+
+	response, err := oidcTokenResponse(tokens.Account, tokens.Session, "", tokens.Session.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	response["refresh_token"] = tokens.RefreshToken
+
+	c.JSON(http.StatusOK, response)
+}
+
+func issueServiceToken(c *gin.Context, client models.OAuthClient) {
+	if client.Public {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unauthorized_client", "error_description": "Public clients cannot use the client_credentials grant"})
+		return
+	}
+
+	scopes := strings.Fields(c.PostForm("scope"))
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, scope := range scopes {
+		if !utils.ContainsString(utils.ServiceScopes, scope) || !utils.ContainsString(client.Scopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_scope", "error_description": "Scope " + scope + " is not allowed for this client"})
+			return
+		}
+	}
+	scope := strings.Join(scopes, " ")
+
+	accessToken, err := utils.GenerateServiceToken(client.ClientID, scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.AccessTokenLifetime.Seconds()),
+		"scope":        scope,
+	})
+}
+
+func oidcTokenResponse(account models.Account, session models.Session, nonce string, authTime time.Time) (gin.H, error) {
+	accessToken, err := generateAccessToken(account, session)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := strings.Fields(session.Scope)
+	idToken, err := utils.GenerateIDToken(utils.IDTokenClaims{
+		AccountID: account.ID,
+		ClientID:  session.ClientID,
+		Nonce:     nonce,
+		AuthTime:  authTime,
+		SessionID: session.ID,
+		Profile:   profileClaims(account, scopes),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.AccessTokenLifetime.Seconds()),
+		"id_token":     idToken,
+		"scope":        session.Scope,
+	}, nil
+}
+
+func profileClaims(account models.Account, scopes []string) gin.H {
+	claims := gin.H{}
+
+	if utils.ContainsString(scopes, "profile") {
+		claims["name"] = strings.TrimSpace(account.FirstName + " " + account.LastName)
+		claims["given_name"] = account.FirstName
+		claims["family_name"] = account.LastName
+		claims["preferred_username"] = account.Username
+		claims["updated_at"] = account.UpdatedAt.Unix()
+	}
+	if utils.ContainsString(scopes, "email") && account.Email != "" {
+		claims["email"] = account.Email
+		claims["email_verified"] = account.EmailVerified
+	}
+	if utils.ContainsString(scopes, "phone") && account.Phone != "" {
+		claims["phone_number"] = account.Phone
+		claims["phone_number_verified"] = account.PhoneVerified
+	}
+
+	return claims
+}
+
+func authenticateClient(c *gin.Context) (models.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	var client models.OAuthClient
+	if clientID == "" || config.DB.Where("client_id = ?", clientID).First(&client).Error != nil {
+		return client, false
+	}
+
+	if client.Public {
+		return client, secret == ""
+	}
+	return client, secret != "" && utils.HashToken(secret) == client.SecretHash
+}
+
+func ssoAccount(c *gin.Context) (models.Account, time.Time, bool) {
+	cookie, err := c.Cookie(ssoCookieName)
+	if err != nil || cookie == "" {
+		return models.Account{}, time.Time{}, false
+	}
+
+	claims, err := utils.ValidateChallengeToken(cookie, utils.ChallengeSSO)
+	if err != nil {
+		return models.Account{}, time.Time{}, false
+	}
+
+	var account models.Account
+	if err := config.DB.Preload("Roles").First(&account, uint(claims["account_id"].(float64))).Error; err != nil {
+		return models.Account{}, time.Time{}, false
+	}
+	if services.CheckAccountStatus(account) != nil {
+		return models.Account{}, time.Time{}, false
+	}
+
+	authTime, _ := claims["auth_time"].(float64)
+	return account, time.Unix(int64(authTime), 0), true
+}
+
+func hasConsent(accountID uint, clientID string, scopes []string) bool {
+	var consent models.OAuthConsent
+	if err := config.DB.Where("account_id = ? AND client_id = ?", accountID, clientID).First(&consent).Error; err != nil {
+		return false
+	}
+
+	for _, scope := range scopes {
+		if !utils.ContainsString(consent.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func saveConsent(accountID uint, clientID string, scopes []string) error {
+	var consent models.OAuthConsent
+	err := config.DB.Where("account_id = ? AND client_id = ?", accountID, clientID).First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return config.DB.Create(&models.OAuthConsent{AccountID: accountID, ClientID: clientID, Scopes: scopes}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range scopes {
+		if !utils.ContainsString(consent.Scopes, scope) {
+			consent.Scopes = append(consent.Scopes, scope)
+		}
+	}
+	return config.DB.Save(&consent).Error
+}
+
+func revokeAuthorizationCodeSession(code models.AuthorizationCode) {
+	if code.SessionID == 0 {
+		return
+	}
+
+	var session models.Session
+	if err := config.DB.First(&session, code.SessionID).Error; err == nil {
+		revokeSession(&session)
+	}
+}
+
+func respondAuthorizationError(c *gin.Context, request authorizationRequest, authErr *authorizationError) {
+	if !authErr.redirect {
+		renderErrorPage(c, http.StatusBadRequest, authErr.description)
+		return
+	}
+
+	params := url.Values{"error": {authErr.code}}
+	if authErr.description != "" {
+		params.Set("error_description", authErr.description)
+	}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+	redirectWithParams(c, request.redirectURI, params)
+}
+
+func redirectWithParams(c *gin.Context, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Invalid redirect URI")
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, target.String())
+}
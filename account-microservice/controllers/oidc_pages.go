@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var scopeDescriptions = map[string]string{
+	"openid":         "Sign you in with your Volga account",
+	"profile":        "Your name and username",
+	"email":          "Your email address",
+	"phone":          "Your phone number",
+	"offline_access": "Stay signed in when you are not using the application",
+}
+
+var oidcPages = template.Must(template.New("layout").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; background: #f3f5f8; margin: 0; }
+main { max-width: 380px; margin: 10vh auto; background: #fff; padding: 32px; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,.1); }
+label { display: block; margin-top: 16px; font-size: 14px; }
+input[type=text], input[type=password] { width: 100%; box-sizing: border-box; padding: 8px; margin-top: 4px; }
+button { margin-top: 24px; padding: 10px 16px; border: 0; border-radius: 4px; background: #1f6feb; color: #fff; cursor: pointer; }
+button.secondary { background: #d0d7de; color: #24292f; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body><main>{{end}}
+
+{{define "request"}}
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="prompt" value="{{.Request.Prompt}}">
+{{end}}
+
+{{define "login"}}{{template "head" .}}
+<h2>Sign in to {{.ClientName}}</h2>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+{{template "request" .}}
+<label>Username <input type="text" name="username" autocomplete="username" required autofocus></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<label>Two-factor code <input type="text" name="code" inputmode="numeric" autocomplete="one-time-code" placeholder="If enabled"></label>
+<button type="submit">Sign in</button>
+</form>
+</main></body></html>{{end}}
+
+{{define "consent"}}{{template "head" .}}
+<h2>{{.ClientName}} wants to access your account</h2>
+<p>Signed in as <strong>{{.Username}}</strong>. The application will be able to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>
+<form method="post" action="/oauth/authorize">
+{{template "request" .}}
+<input type="hidden" name="ticket" value="{{.Ticket}}">
+<button type="submit" name="decision" value="approve">Allow</button>
+<button type="submit" name="decision" value="deny" class="secondary">Deny</button>
+</form>
+</main></body></html>{{end}}
+
+{{define "error"}}{{template "head" .}}
+<h2>Authorization failed</h2>
+<p class="error">{{.Error}}</p>
+</main></body></html>{{end}}
+`))
+
+type oidcPage struct {
+	Title      string
+	ClientName string
+	Request    authorizationRequest
+	Error      string
+	Username   string
+	Scopes     []string
+	Ticket     string
+}
+
+func renderLoginPage(c *gin.Context, status int, request authorizationRequest, message string) {
+	renderOIDCPage(c, status, "login", oidcPage{
+		Title:      "Sign in",
+		ClientName: request.client.Name,
+		Request:    request,
+		Error:      message,
+	})
+}
+
+func renderConsentPage(c *gin.Context, request authorizationRequest, account models.Account, ticket string) {
+	scopes := make([]string, 0, len(request.scopes))
+	for _, scope := range request.scopes {
+		if description, ok := scopeDescriptions[scope]; ok {
+			scopes = append(scopes, description)
+		}
+	}
+
+	renderOIDCPage(c, http.StatusOK, "consent", oidcPage{
+		Title:      "Allow access",
+		ClientName: request.client.Name,
+		Request:    request,
+		Username:   account.Username,
+		Scopes:     scopes,
+		Ticket:     ticket,
+	})
+}
+
+func renderErrorPage(c *gin.Context, status int, message string) {
+	renderOIDCPage(c, status, "error", oidcPage{Title: "Authorization failed", Error: message})
+}
+
+func renderOIDCPage(c *gin.Context, status int, name string, page oidcPage) {
+	var buf bytes.Buffer
+	if err := oidcPages.ExecuteTemplate(&buf, name, page); err != nil {
+		log.Printf("Failed to render %s page: %v", name, err)
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ChangePasswordChallenge(c *gin.Context) {
+	var input struct {
+		Challenge   string `json:"challenge" binding:"required"`
+		NewPassword string `json:"newPassword" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateChallengeToken(input.Challenge, utils.ChallengePasswordChange)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.Preload("Roles").First(&account, uint(claims["account_id"].(float64))).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account not found"})
+		return
+	}
+
+	if !account.MustChangePassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password change is not required"})
+		return
+	}
+
+	if !setPassword(c, &account, input.NewPassword) {
+		return
+	}
+	account.MustChangePassword = false
+
+	if err := saveAccountPassword(config.DB, &account); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	deviceName, _ := claims["device"].(string)
+	response, err := signInResponse(c, account, deviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// completeSignIn issues tokens, or a password change challenge when the
+// account still has to replace an initial password.
+func completeSignIn(c *gin.Context, account models.Account, deviceName string, extra gin.H) {
+	if err := services.CheckAccountStatus(account); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	if account.MustChangePassword {
+		challenge, err := utils.GenerateChallengeToken(account.ID, utils.ChallengePasswordChange, deviceName)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate challenge"})
+			return
+		}
+
+		response := gin.H{
+			"passwordChangeRequired": true,
+			"challenge":              challenge,
+			"expiresIn":              int(utils.ChallengeLifetime.Seconds()),
+		}
+		for key, value := range extra {
+			response[key] = value
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	response, err := signInResponse(c, account, deviceName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// setPassword checks the password policy and history and stores the new hash
+// on the account. It writes the error response and returns false on failure.
+func setPassword(c *gin.Context, account *models.Account, password string) bool {
+	if err := services.CurrentPasswordPolicy().Validate(account.Username, password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if err := services.CheckPasswordReuse(*account, password); err != nil {
+		if errors.Is(err, services.ErrPasswordReused) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password history"})
+		}
+		return false
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password encryption failed"})
+		return false
+	}
+
+	account.Password = passwordHash
+	return true
+}
+
+func saveAccountPassword(db *gorm.DB, account *models.Account) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Roles", "Specializations").Save(account).Error; err != nil {
+			return err
+		}
+		return services.RecordPasswordHistory(tx, account.ID, account.Password)
+	})
+}
+
+func upgradePasswordHash(account *models.Account, password string) {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for account %d: %v", account.ID, err)
+		return
+	}
+
+	if err := config.DB.Model(account).Update("password", passwordHash).Error; err != nil {
+		log.Printf("Failed to upgrade password hash for account %d: %v", account.ID, err)
+	}
+}
+
+func createAccount(db *gorm.DB, account *models.Account) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+		return services.RecordPasswordHistory(tx, account.ID, account.Password)
+	})
+}
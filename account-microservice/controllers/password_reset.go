@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/notifier"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func RequestPasswordReset(c *gin.Context) {
+	var input struct {
+		Login string `json:"login" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If the account exists, password reset instructions have been sent"}
+
+	login := strings.TrimSpace(input.Login)
+	var account models.Account
+	if err := config.DB.Where("username = ? OR (email <> '' AND LOWER(email) = LOWER(?))", login, login).First(&account).Error; err != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	if account.Email == "" {
+		log.Printf("Password reset requested for account %d without an email address", account.ID)
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	token, err := utils.RandomString(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate reset token"})
+		return
+	}
+
+	lifetime := config.GetDuration("PASSWORD_RESET_TTL", 30*time.Minute)
+	resetToken := models.PasswordResetToken{
+		AccountID: account.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(lifetime),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ? AND used_at IS NULL", account.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&resetToken).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	message := passwordResetMessage(account, token, lifetime)
+	go func() {
+		if err := notifier.Send(message); err != nil {
+			log.Printf("Failed to send password reset to account %d: %v", account.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+func ConfirmPasswordReset(c *gin.Context) {
+	var input struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"newPassword" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	if err := config.DB.
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(strings.TrimSpace(input.Token)), time.Now()).
+		First(&resetToken).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, resetToken.AccountID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if !setPassword(c, &account, input.NewPassword) {
+		return
+	}
+	account.MustChangePassword = false
+
+	var used bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&resetToken).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			used = true
+			return nil
+		}
+		return saveAccountPassword(tx, &account)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if used {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+
+	if err := services.RevokeAllSessions(account.ID, "password reset"); err != nil {
+		log.Printf("Failed to revoke sessions of account %d after password reset: %v", account.ID, err)
+	}
+	if err := services.ClearLoginFailures(services.UserLockoutKey(account.Username)); err != nil {
+		log.Printf("Failed to clear sign-in failures of account %d: %v", account.ID, err)
+	}
+	recordSecurityEvent(c, account.ID, models.SecurityEventPasswordReset, "password reset with emailed token")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+func passwordResetMessage(account models.Account, token string, lifetime time.Duration) notifier.Message {
+	instructions := "Use this token to reset your password: " + token
+	if resetURL := os.Getenv("PASSWORD_RESET_URL"); resetURL != "" {
+		separator := "?"
+		if strings.Contains(resetURL, "?") {
+			separator = "&"
+		}
+		instructions = "Open this link to reset your password: " + resetURL + separator + "token=" + url.QueryEscape(token)
+	}
+
+	return notifier.Message{
+		Channel: notifier.ChannelEmail,
+		To:      account.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\nA password reset was requested for your account.\n%s\n\nThe token expires in %d minutes and can be used once. If you did not request a reset, ignore this message.",
+			account.Username, instructions, int(lifetime.Minutes())),
+	}
+}
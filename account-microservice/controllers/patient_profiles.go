@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type patientSearchResult struct {
+	ID        uint                  `json:"id"`
+	LastName  string                `json:"lastName"`
+	FirstName string                `json:"firstName"`
+	Profile   models.PatientProfile `json:"profile"`
+}
+
+func GetCurrentPatientProfile(c *gin.Context) {
+	respondPatientProfile(c, c.GetUint("account_id"))
+}
+
+func UpdateCurrentPatientProfile(c *gin.Context) {
+	updatePatientProfile(c, c.GetUint("account_id"))
+}
+
+func GetPatientProfile(c *gin.Context) {
+	accountID, ok := findAccountID(c)
+	if !ok {
+		return
+	}
+	respondPatientProfile(c, accountID)
+}
+
+func UpdatePatientProfile(c *gin.Context) {
+	accountID, ok := findAccountID(c)
+	if !ok {
+		return
+	}
+	updatePatientProfile(c, accountID)
+}
+
+// SearchPatients finds patients by SNILS, OMS policy number or last name,
+// optionally narrowed down by date of birth.
+func SearchPatients(c *gin.Context) {
+	query := config.DB.Select("patient_profiles.*").
+		Joins("JOIN accounts ON accounts.id = patient_profiles.account_id AND accounts.deleted_at IS NULL").
+		Order("accounts.last_name, accounts.first_name, accounts.id").
+		Limit(50)
+
+	filtered := false
+	if value := c.Query("snils"); value != "" {
+		snils, err := utils.NormalizeSNILS(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("patient_profiles.snils = ?", snils)
+		filtered = true
+	}
+	if value := c.Query("oms"); value != "" {
+		oms, err := utils.NormalizeOMS(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("patient_profiles.oms_number = ?", oms)
+		filtered = true
+	}
+	if value := strings.TrimSpace(c.Query("lastName")); value != "" {
+		query = query.Where("accounts.last_name ILIKE ?", value)
+		filtered = true
+	}
+	if value := c.Query("birthDate"); value != "" {
+		birthDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'birthDate' parameter, expected YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("patient_profiles.birth_date = ?", birthDate)
+	}
+
+	if !filtered {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify 'snils', 'oms' or 'lastName'"})
+		return
+	}
+
+	var profiles []models.PatientProfile
+	if err := query.Find(&profiles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search patients"})
+		return
+	}
+
+	accountIDs := make([]uint, 0, len(profiles))
+	for _, profile := range profiles {
+		accountIDs = append(accountIDs, profile.AccountID)
+	}
+	var accounts []models.Account
+	if err := config.DB.Where("id IN ?", accountIDs).Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search patients"})
+		return
+	}
+	byID := make(map[uint]models.Account, len(accounts))
+	for _, account := range accounts {
+		byID[account.ID] = account
+	}
+
+	results := make([]patientSearchResult, 0, len(profiles))
+	for _, profile := range profiles {
+		account := byID[profile.AccountID]
+		results = append(results, patientSearchResult{ID: account.ID, LastName: account.LastName, FirstName: account.FirstName, Profile: profile})
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func respondPatientProfile(c *gin.Context, accountID uint) {
+	profile, err := loadPatientProfile(accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve patient profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// updatePatientProfile applies a partial update. Fields that are not sent
+// stay unchanged; an empty string clears a field.
+func updatePatientProfile(c *gin.Context, accountID uint) {
+	var input struct {
+		Patronymic            *string `json:"patronymic" binding:"omitempty,max=64"`
+		BirthDate             *string `json:"birthDate"`
+		Sex                   *string `json:"sex"`
+		OMSNumber             *string `json:"omsNumber"`
+		SNILS                 *string `json:"snils"`
+		Address               *string `json:"address" binding:"omitempty,max=512"`
+		EmergencyContactName  *string `json:"emergencyContactName" binding:"omitempty,max=128"`
+		EmergencyContactPhone *string `json:"emergencyContactPhone"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := loadPatientProfile(accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve patient profile"})
+		return
+	}
+
+	if input.Patronymic != nil {
+		profile.Patronymic = strings.TrimSpace(*input.Patronymic)
+	}
+	if input.BirthDate != nil {
+		profile.BirthDate = nil
+		if *input.BirthDate != "" {
+			birthDate, err := time.Parse("2006-01-02", *input.BirthDate)
+			if err != nil || birthDate.After(time.Now()) || birthDate.Year() < 1900 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'birthDate', expected YYYY-MM-DD"})
+				return
+			}
+			profile.BirthDate = &birthDate
+		}
+	}
+	if input.Sex != nil {
+		sex := strings.ToLower(strings.TrimSpace(*input.Sex))
+		if sex != "" && sex != models.SexMale && sex != models.SexFemale {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'sex', expected 'male' or 'female'"})
+			return
+		}
+		profile.Sex = sex
+	}
+	if input.OMSNumber != nil {
+		profile.OMSNumber = ""
+		if strings.TrimSpace(*input.OMSNumber) != "" {
+			if profile.OMSNumber, err = utils.NormalizeOMS(*input.OMSNumber); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+	if input.SNILS != nil {
+		profile.SNILS = ""
+		if strings.TrimSpace(*input.SNILS) != "" {
+			if profile.SNILS, err = utils.NormalizeSNILS(*input.SNILS); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+	if input.Address != nil {
+		profile.Address = strings.TrimSpace(*input.Address)
+	}
+	if input.EmergencyContactName != nil {
+		profile.EmergencyContactName = strings.TrimSpace(*input.EmergencyContactName)
+	}
+	if input.EmergencyContactPhone != nil {
+		profile.EmergencyContactPhone = ""
+		if strings.TrimSpace(*input.EmergencyContactPhone) != "" {
+			if profile.EmergencyContactPhone, err = utils.NormalizePhone(*input.EmergencyContactPhone); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	if identifierTaken("oms_number", profile.OMSNumber, accountID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "OMS policy number is already registered to another patient"})
+		return
+	}
+	if identifierTaken("snils", profile.SNILS, accountID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "SNILS is already registered to another patient"})
+		return
+	}
+
+	if err := config.DB.Save(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update patient profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func loadPatientProfile(accountID uint) (models.PatientProfile, error) {
+	var profile models.PatientProfile
+	err := config.DB.First(&profile, "account_id = ?", accountID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.PatientProfile{AccountID: accountID}, nil
+	}
+	return profile, err
+}
+
+func identifierTaken(column, value string, accountID uint) bool {
+	if value == "" {
+		return false
+	}
+	var count int64
+	config.DB.Model(&models.PatientProfile{}).Where(column+" = ? AND account_id <> ?", value, accountID).Count(&count)
+	return count > 0
+}
+
+func findAccountID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return 0, false
+	}
+
+	var count int64
+	config.DB.Model(&models.Account{}).Where("id = ?", id).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+var roleEventTypes = []string{
+	models.SecurityEventRoleAssigned,
+	models.SecurityEventRoleRemoved,
+	models.SecurityEventRoleCreated,
+	models.SecurityEventRoleUpdated,
+	models.SecurityEventRoleDeleted,
+}
+
+type roleResponse struct {
+	models.Role
+	Accounts int64 `json:"accounts"`
+}
+
+func GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+
+	response := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		count := config.DB.Model(&role).Association("Accounts").Count()
+		response = append(response, roleResponse{Role: role, Accounts: count})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func GetRole(c *gin.Context) {
+	role, ok := findRole(c)
+	if !ok {
+		return
+	}
+
+	count := config.DB.Model(&role).Association("Accounts").Count()
+	c.JSON(http.StatusOK, roleResponse{Role: role, Accounts: count})
+}
+
+func GetPermissions(c *gin.Context) {
+	var permissions []models.Permission
+	if err := config.DB.Order("name").Find(&permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+func CreateRole(c *gin.Context) {
+	var input struct {
+		Name           string   `json:"name" binding:"required"`
+		MFARequired    bool     `json:"mfaRequired"`
+		HospitalScoped bool     `json:"hospitalScoped"`
+		Permissions    []string `json:"permissions"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := models.NormalizeRoleName(input.Name)
+	if !roleNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name must be 2-32 characters of lowercase letters, digits, '-' or '_'"})
+		return
+	}
+
+	permissions, err := findPermissions(input.Permissions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.Role{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+
+	role := models.Role{Name: name, MFARequired: input.MFARequired, HospitalScoped: input.HospitalScoped, Permissions: permissions}
+	if err := config.DB.Create(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+
+	recordSecurityEvent(c, 0, models.SecurityEventRoleCreated, fmt.Sprintf("role %s created with permissions %s, hospitalScoped %t", role.Name, permissionNames(permissions), role.HospitalScoped))
+	c.JSON(http.StatusCreated, roleResponse{Role: role})
+}
+
+func UpdateRole(c *gin.Context) {
+	var input struct {
+		MFARequired    *bool    `json:"mfaRequired"`
+		HospitalScoped *bool    `json:"hospitalScoped"`
+		Permissions    []string `json:"permissions"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, ok := findRole(c)
+	if !ok {
+		return
+	}
+
+	if input.HospitalScoped != nil && !*input.HospitalScoped && role.HospitalScoped {
+		var count int64
+		config.DB.Model(&models.HospitalAffiliation{}).Where("role_id = ?", role.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Role is still used by %d hospital affiliations", count)})
+			return
+		}
+	}
+
+	var permissions []*models.Permission
+	if input.Permissions != nil {
+		var err error
+		permissions, err = findPermissions(input.Permissions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if input.MFARequired != nil {
+			if err := tx.Model(&role).Update("mfa_required", *input.MFARequired).Error; err != nil {
+				return err
+			}
+		}
+		if input.HospitalScoped != nil {
+			if err := tx.Model(&role).Update("hospital_scoped", *input.HospitalScoped).Error; err != nil {
+				return err
+			}
+		}
+		if input.Permissions == nil {
+			return nil
+		}
+		return tx.Model(&role).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	if input.MFARequired != nil {
+		role.MFARequired = *input.MFARequired
+		recordSecurityEvent(c, 0, models.SecurityEventRoleUpdated, fmt.Sprintf("role %s mfaRequired set to %t", role.Name, role.MFARequired))
+	}
+	if input.HospitalScoped != nil && *input.HospitalScoped != role.HospitalScoped {
+		role.HospitalScoped = *input.HospitalScoped
+		revokeRoleHolders(role, "role scope changed")
+		recordSecurityEvent(c, 0, models.SecurityEventRoleUpdated, fmt.Sprintf("role %s hospitalScoped set to %t", role.Name, role.HospitalScoped))
+	}
+	if input.Permissions != nil {
+		role.Permissions = permissions
+		revokeRoleHolders(role, "role permissions changed")
+		recordSecurityEvent(c, 0, models.SecurityEventRoleUpdated, fmt.Sprintf("role %s permissions set to %s", role.Name, permissionNames(permissions)))
+	}
+
+	count := config.DB.Model(&role).Association("Accounts").Count()
+	c.JSON(http.StatusOK, roleResponse{Role: role, Accounts: count})
+}
+
+func DeleteRole(c *gin.Context) {
+	role, ok := findRole(c)
+	if !ok {
+		return
+	}
+
+	if _, builtIn := models.DefaultRolePermissions[role.Name]; builtIn {
+		c.JSON(http.StatusConflict, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+
+	if count := config.DB.Model(&role).Association("Accounts").Count(); count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Role is still assigned to %d accounts", count)})
+		return
+	}
+
+	var affiliations int64
+	config.DB.Model(&models.HospitalAffiliation{}).Where("role_id = ?", role.ID).Count(&affiliations)
+	if affiliations > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Role is still used by %d hospital affiliations", affiliations)})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+
+	recordSecurityEvent(c, 0, models.SecurityEventRoleDeleted, "role "+role.Name+" deleted")
+	c.Status(http.StatusOK)
+}
+
+func UpdateRolePolicy(c *gin.Context) {
+	var input struct {
+		MFARequired *bool `json:"mfaRequired" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.Role
+	if err := config.DB.Where("name = ?", models.NormalizeRoleName(c.Param("name"))).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if err := config.DB.Model(&role).Update("mfa_required", *input.MFARequired).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role policy"})
+		return
+	}
+
+	recordSecurityEvent(c, 0, models.SecurityEventRoleUpdated, fmt.Sprintf("role %s mfaRequired set to %t", role.Name, *input.MFARequired))
+	c.JSON(http.StatusOK, role)
+}
+
+// GetRoleAudit lists role changes and role assignments, newest first.
+func GetRoleAudit(c *gin.Context) {
+	query := config.DB.Where("type IN ?", roleEventTypes).Order("created_at DESC, id DESC")
+
+	if accountID := c.Query("accountId"); accountID != "" {
+		id, err := strconv.ParseUint(accountID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'accountId' parameter"})
+			return
+		}
+		query = query.Where("account_id = ?", id)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("details LIKE ?", "role "+models.NormalizeRoleName(role)+" %")
+	}
+
+	from, count := 0, 100
+	if value := c.Query("from"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'from' parameter"})
+			return
+		}
+		from = parsed
+	}
+	if value := c.Query("count"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'count' parameter"})
+			return
+		}
+		count = parsed
+	}
+
+	var events []models.SecurityEvent
+	if err := query.Offset(from).Limit(count).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve role audit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func findRole(c *gin.Context) (models.Role, bool) {
+	var role models.Role
+	if err := config.DB.Preload("Permissions").Where("name = ?", models.NormalizeRoleName(c.Param("name"))).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return role, false
+	}
+	return role, true
+}
+
+// findRoles resolves role names case-insensitively and rejects names that
+// do not match an existing role.
+func findRoles(names []string) ([]*models.Role, error) {
+	if names == nil {
+		return nil, nil
+	}
+
+	roles := make([]*models.Role, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = models.NormalizeRoleName(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var role models.Role
+		if err := config.DB.Where("name = ?", name).First(&role).Error; err != nil {
+			return nil, fmt.Errorf("Unknown role: %s", name)
+		}
+		roles = append(roles, &role)
+	}
+	return roles, nil
+}
+
+func findPermissions(names []string) ([]*models.Permission, error) {
+	permissions := make([]*models.Permission, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var permission models.Permission
+		if err := config.DB.Where("name = ?", name).First(&permission).Error; err != nil {
+			return nil, fmt.Errorf("Unknown permission: %s", name)
+		}
+		permissions = append(permissions, &permission)
+	}
+	return permissions, nil
+}
+
+func permissionNames(permissions []*models.Permission) string {
+	names := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		names = append(names, permission.Name)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// recordRoleChanges writes a role_assigned or role_removed event for every
+// difference between the account's previous and new roles.
+func recordRoleChanges(c *gin.Context, accountID uint, before []*models.Role, after []*models.Role) {
+	had := make(map[string]bool)
+	for _, role := range before {
+		had[role.Name] = true
+	}
+	has := make(map[string]bool)
+	for _, role := range after {
+		has[role.Name] = true
+		if !had[role.Name] {
+			recordSecurityEvent(c, accountID, models.SecurityEventRoleAssigned, "role "+role.Name+" assigned")
+		}
+	}
+	for _, role := range before {
+		if !has[role.Name] {
+			recordSecurityEvent(c, accountID, models.SecurityEventRoleRemoved, "role "+role.Name+" removed")
+		}
+	}
+}
+
+// revokeRoleHolders revokes the access tokens of every account holding the
+// role, directly or in a hospital, so that the new permissions take effect
+// immediately.
+func revokeRoleHolders(role models.Role, reason string) {
+	var accountIDs []uint
+	if err := config.DB.Table("account_roles").Where("role_id = ?", role.ID).Pluck("account_id", &accountIDs).Error; err != nil {
+		log.Printf("Failed to list accounts holding role %s: %v", role.Name, err)
+		return
+	}
+	var affiliatedIDs []uint
+	if err := config.DB.Model(&models.HospitalAffiliation{}).Where("role_id = ?", role.ID).Distinct().Pluck("account_id", &affiliatedIDs).Error; err != nil {
+		log.Printf("Failed to list accounts affiliated with role %s: %v", role.Name, err)
+		return
+	}
+	accountIDs = uniqueIDs(append(accountIDs, affiliatedIDs...))
+
+	for _, accountID := range accountIDs {
+		if err := services.RevokeAccount(accountID, reason); err != nil {
+			log.Printf("Failed to revoke tokens of account %d: %v", accountID, err)
+		}
+	}
+}
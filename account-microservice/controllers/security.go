@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"log"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestActor is the account responsible for the request: the
+// administrator behind an impersonation token, otherwise the token's account.
+func requestActor(c *gin.Context) uint {
+	if impersonatorID := c.GetUint("impersonator_id"); impersonatorID != 0 {
+		return impersonatorID
+	}
+	return c.GetUint("account_id")
+}
+
+func recordSecurityEvent(c *gin.Context, accountID uint, eventType string, details string) {
+	event := models.SecurityEvent{
+		AccountID: accountID,
+		ActorID:   requestActor(c),
+		Type:      eventType,
+		Details:   details,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	if err := config.DB.Create(&event).Error; err != nil {
+		log.Printf("Failed to record security event %s for account %d: %v", eventType, accountID, err)
+	}
+}
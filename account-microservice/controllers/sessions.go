@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func GetCurrentSessions(c *gin.Context) {
+	listSessions(c, c.GetUint("account_id"))
+}
+
+func RevokeCurrentSession(c *gin.Context) {
+	revokeAccountSession(c, c.GetUint("account_id"))
+}
+
+func GetAccountSessions(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	listSessions(c, uint(accountID))
+}
+
+func RevokeAccountSession(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	revokeAccountSession(c, uint(accountID))
+}
+
+func listSessions(c *gin.Context, accountID uint) {
+	var sessions []models.Session
+	if err := config.DB.
+		Where("account_id = ? AND revoked_at IS NULL AND last_used_at > ?", accountID, time.Now().Add(-utils.RefreshTokenLifetime)).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions"})
+		return
+	}
+
+	currentSessionID := c.GetUint("session_id")
+	result := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"id":         session.ID,
+			"deviceName": session.DeviceName,
+			"userAgent":  session.UserAgent,
+			"ip":         session.IP,
+			"createdAt":  session.CreatedAt,
+			"lastUsedAt": session.LastUsedAt,
+			"current":    session.ID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func revokeAccountSession(c *gin.Context, accountID uint) {
+	var session models.Session
+	if err := config.DB.Where("id = ? AND account_id = ?", c.Param("sessionId"), accountID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := revokeSession(&session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func startSession(c *gin.Context, account models.Account, deviceName string) (models.Session, string, error) {
+	return startClientSession(c, account, deviceName, "", "")
+}
+
+func startClientSession(c *gin.Context, account models.Account, deviceName, clientID, scope string) (models.Session, string, error) {
+	refreshToken, err := utils.GenerateRefreshToken(account.ID)
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	familyID, err := utils.RandomString(16)
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		AccountID:  account.ID,
+		FamilyID:   familyID,
+		ClientID:   clientID,
+		Scope:      scope,
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastUsedAt: now,
+	}
+	token := models.Token{
+		Token:     refreshToken,
+		AccountID: account.ID,
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(utils.RefreshTokenLifetime),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+func revokeSession(session *models.Session) error {
+	now := time.Now()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(session).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Token{}).
+			Where("family_id = ? AND revoked_at IS NULL", session.FamilyID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return services.RevokeSession(session.ID, "session revoked")
+}
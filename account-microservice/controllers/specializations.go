@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type specializationResponse struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Doctors int64  `json:"doctors"`
+}
+
+func GetSpecializations(c *gin.Context) {
+	var response []specializationResponse
+	err := config.DB.Model(&models.Specialization{}).
+		Select("specializations.id, specializations.name, COUNT(doctor_specializations.account_id) AS doctors").
+		Joins("LEFT JOIN doctor_specializations ON doctor_specializations.specialization_id = specializations.id").
+		Group("specializations.id").
+		Order("specializations.name").
+		Scan(&response).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve specializations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func GetSpecialization(c *gin.Context) {
+	specialization, ok := findSpecialization(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	count := config.DB.Model(&specialization).Association("Doctors").Count()
+	c.JSON(http.StatusOK, specializationResponse{ID: specialization.ID, Name: specialization.Name, Doctors: count})
+}
+
+func CreateSpecialization(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := normalizeSpecializationName(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specialization name is required"})
+		return
+	}
+	if specializationNameTaken(name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Specialization already exists"})
+		return
+	}
+
+	specialization := models.Specialization{Name: name}
+	if err := config.DB.Create(&specialization).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create specialization"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, specializationResponse{ID: specialization.ID, Name: specialization.Name})
+}
+
+func UpdateSpecialization(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	specialization, ok := findSpecialization(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	name := normalizeSpecializationName(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specialization name is required"})
+		return
+	}
+	if specializationNameTaken(name, specialization.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Specialization already exists, merge it instead"})
+		return
+	}
+
+	if err := config.DB.Model(&specialization).Update("name", name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename specialization"})
+		return
+	}
+
+	specialization.Name = name
+	count := config.DB.Model(&specialization).Association("Doctors").Count()
+	c.JSON(http.StatusOK, specializationResponse{ID: specialization.ID, Name: specialization.Name, Doctors: count})
+}
+
+func DeleteSpecialization(c *gin.Context) {
+	specialization, ok := findSpecialization(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if count := config.DB.Model(&specialization).Association("Doctors").Count(); count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Specialization is still assigned to " + strconv.FormatInt(count, 10) + " doctors"})
+		return
+	}
+
+	if err := config.DB.Delete(&specialization).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete specialization"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// MergeSpecializations moves the doctors of the source specializations to the
+// target and deletes the sources.
+func MergeSpecializations(c *gin.Context) {
+	var input struct {
+		SourceIDs []uint `json:"sourceIds" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, ok := findSpecialization(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	for _, sourceID := range input.SourceIDs {
+		if sourceID == target.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A specialization cannot be merged into itself"})
+			return
+		}
+	}
+
+	var sources []models.Specialization
+	if err := config.DB.Where("id IN ?", input.SourceIDs).Find(&sources).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve specializations"})
+		return
+	}
+	if len(sources) != len(uniqueIDs(input.SourceIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Specialization not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"INSERT INTO doctor_specializations (account_id, specialization_id) SELECT DISTINCT account_id, ? FROM doctor_specializations WHERE specialization_id IN ? ON CONFLICT DO NOTHING",
+			target.ID, input.SourceIDs,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM doctor_specializations WHERE specialization_id IN ?", input.SourceIDs).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", input.SourceIDs).Delete(&models.Specialization{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge specializations"})
+		return
+	}
+
+	count := config.DB.Model(&target).Association("Doctors").Count()
+	c.JSON(http.StatusOK, specializationResponse{ID: target.ID, Name: target.Name, Doctors: count})
+}
+
+func UpdateDoctorSpecializations(c *gin.Context) {
+	var input struct {
+		SpecializationIDs []uint `json:"specializationIds" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctor, ok := findDoctor(c)
+	if !ok {
+		return
+	}
+
+	ids := uniqueIDs(input.SpecializationIDs)
+	specializations := make([]*models.Specialization, 0, len(ids))
+	if len(ids) > 0 {
+		if err := config.DB.Where("id IN ?", ids).Order("name").Find(&specializations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve specializations"})
+			return
+		}
+		if len(specializations) != len(ids) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown specialization"})
+			return
+		}
+	}
+
+	if err := config.DB.Model(&doctor).Association("Specializations").Replace(specializations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update specializations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              doctor.ID,
+		"lastName":        doctor.LastName,
+		"firstName":       doctor.FirstName,
+		"specializations": specializations,
+	})
+}
+
+func findSpecialization(c *gin.Context, idParam string) (models.Specialization, bool) {
+	var specialization models.Specialization
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid specialization ID"})
+		return specialization, false
+	}
+	if err := config.DB.First(&specialization, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Specialization not found"})
+		return specialization, false
+	}
+	return specialization, true
+}
+
+func normalizeSpecializationName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func specializationNameTaken(name string, exceptID uint) bool {
+	var count int64
+	config.DB.Model(&models.Specialization{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).Count(&count)
+	return count > 0
+}
+
+func hasRole(account models.Account, name string) bool {
+	for _, role := range account.Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
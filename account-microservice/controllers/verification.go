@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/notifier"
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	verificationCodeLength     = 6
+	verificationMaxAttempts    = 5
+	verificationResendInterval = time.Minute
+)
+
+func SendContactVerification(c *gin.Context) {
+	var input struct {
+		Channel string `json:"channel" binding:"required,oneof=email phone"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		return
+	}
+
+	destination, verified := contactOf(account, input.Channel)
+	if destination == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Account has no %s to verify", input.Channel)})
+		return
+	}
+	if verified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The %s is already verified", input.Channel)})
+		return
+	}
+
+	var last models.ContactVerification
+	err := config.DB.Where("account_id = ? AND channel = ?", account.ID, input.Channel).Order("created_at DESC").First(&last).Error
+	if err == nil && time.Since(last.CreatedAt) < verificationResendInterval {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "A verification code was sent recently, try again later"})
+		return
+	}
+
+	if err := sendVerificationCode(account, input.Channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification code"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification code sent"})
+}
+
+func ConfirmContactVerification(c *gin.Context) {
+	var input struct {
+		Channel string `json:"channel" binding:"required,oneof=email phone"`
+		Code    string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := config.DB.First(&account, c.GetUint("account_id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account not found"})
+		return
+	}
+
+	destination, _ := contactOf(account, input.Channel)
+
+	var verification models.ContactVerification
+	if err := config.DB.
+		Where("account_id = ? AND channel = ? AND destination = ? AND expires_at > ?", account.ID, input.Channel, destination, time.Now()).
+		Order("created_at DESC").
+		First(&verification).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pending verification, request a new code"})
+		return
+	}
+
+	result := config.DB.Model(&models.ContactVerification{}).
+		Where("id = ? AND attempts < ?", verification.ID, verificationMaxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify contact"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many invalid codes, request a new code"})
+		return
+	}
+
+	if utils.HashToken(strings.TrimSpace(input.Code)) != verification.CodeHash {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	field := "email_verified"
+	if input.Channel == models.ContactPhone {
+		field = "phone_verified"
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Updates(map[string]interface{}{field: true, "unverified": false}).Error; err != nil {
+			return err
+		}
+		return tx.Where("account_id = ? AND channel = ?", account.ID, input.Channel).Delete(&models.ContactVerification{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify contact"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact verified, refresh your access token to apply it"})
+}
+
+// sendVerificationCodes sends codes to every unverified contact of the
+// account and logs delivery failures.
+func sendVerificationCodes(account models.Account) {
+	for _, channel := range []string{models.ContactEmail, models.ContactPhone} {
+		destination, verified := contactOf(account, channel)
+		if destination == "" || verified {
+			continue
+		}
+		if err := sendVerificationCode(account, channel); err != nil {
+			log.Printf("Failed to send %s verification to account %d: %v", channel, account.ID, err)
+		}
+	}
+}
+
+func sendVerificationCode(account models.Account, channel string) error {
+	destination, _ := contactOf(account, channel)
+	if destination == "" {
+		return errors.New("no destination for " + channel)
+	}
+
+	code, err := utils.RandomDigits(verificationCodeLength)
+	if err != nil {
+		return err
+	}
+
+	lifetime := config.GetDuration("VERIFICATION_CODE_TTL", 15*time.Minute)
+	verification := models.ContactVerification{
+		AccountID:   account.ID,
+		Channel:     channel,
+		Destination: destination,
+		CodeHash:    utils.HashToken(code),
+		ExpiresAt:   time.Now().Add(lifetime),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ? AND channel = ?", account.ID, channel).Delete(&models.ContactVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&verification).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	message := notifier.Message{
+		Channel: notifier.ChannelEmail,
+		To:      destination,
+		Subject: "Verification code",
+		Body:    fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(lifetime.Minutes())),
+	}
+	if channel == models.ContactPhone {
+		message.Channel = notifier.ChannelSMS
+	}
+
+	return notifier.Send(message)
+}
+
+func contactOf(account models.Account, channel string) (destination string, verified bool) {
+	if channel == models.ContactPhone {
+		return account.Phone, account.PhoneVerified
+	}
+	return account.Email, account.EmailVerified
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"net/http"
+
+	"account-microservice/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.AccessKeys.JWKS()})
+}
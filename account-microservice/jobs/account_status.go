@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/services"
+)
+
+// StartAccountStatusScheduler applies suspensions and reactivations that
+// were scheduled with a future effective date.
+func StartAccountStatusScheduler() {
+	interval := config.GetDuration("ACCOUNT_STATUS_INTERVAL", time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			services.ApplyDueStatusChanges()
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/services"
+)
+
+// StartAuditRetention archives audit events older than AUDIT_RETENTION.
+func StartAuditRetention() {
+	retention := config.GetDuration("AUDIT_RETENTION", 90*24*time.Hour)
+	interval := config.GetDuration("AUDIT_ARCHIVE_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			archived, err := services.ArchiveAuditEvents(time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Failed to archive audit events: %v", err)
+			}
+			if archived > 0 {
+				log.Printf("Archived %d audit events", archived)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/services"
+	"account-microservice/utils"
+)
+
+func StartTokenSweeper() {
+	interval := config.GetDuration("TOKEN_SWEEP_INTERVAL", time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sweepExpiredTokens()
+			<-ticker.C
+		}
+	}()
+}
+
+func sweepExpiredTokens() {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.Token{})
+	if result.Error != nil {
+		log.Printf("Failed to sweep expired refresh tokens: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Swept %d expired refresh tokens", result.RowsAffected)
+	}
+
+	cutoff := time.Now().Add(-utils.RefreshTokenLifetime)
+	result = config.DB.Where("last_used_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{})
+	if result.Error != nil {
+		log.Printf("Failed to sweep stale sessions: %v", result.Error)
+	}
+
+	result = config.DB.Where("expires_at < ?", time.Now()).Delete(&models.Revocation{})
+	if result.Error != nil {
+		log.Printf("Failed to sweep expired revocations: %v", result.Error)
+	}
+
+	result = config.DB.Where("expires_at < ? OR used_at IS NOT NULL", time.Now()).Delete(&models.PasswordResetToken{})
+	if result.Error != nil {
+		log.Printf("Failed to sweep password reset tokens: %v", result.Error)
+	}
+
+	result = config.DB.Where("expires_at < ?", time.Now()).Delete(&models.ContactVerification{})
+	if result.Error != nil {
+		log.Printf("Failed to sweep contact verifications: %v", result.Error)
+	}
+
+	result = config.DB.Where("expires_at < ?", time.Now()).Delete(&models.AuthorizationCode{})
+	if result.Error != nil {
+		log.Printf("Failed to sweep authorization codes: %v", result.Error)
+	}
+
+	if _, err := services.SweepLoginAttempts(); err != nil {
+		log.Printf("Failed to sweep login attempts: %v", err)
+	}
+}
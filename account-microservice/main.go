@@ -1,9 +1,14 @@
 package main
 
 import (
+	"os"
+
 	"account-microservice/config"
+	"account-microservice/jobs"
 	"account-microservice/models"
+	"account-microservice/notifier"
 	"account-microservice/routes"
+	"account-microservice/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,15 +19,52 @@ func main() {
         &models.Account{},
         &models.Role{},
         &models.Token{},
-        &models.Doctor{},
+        &models.DoctorProfile{},
+        &models.DoctorPhoto{},
+        &models.PatientProfile{},
+        &models.Guardianship{},
+        &models.HospitalAffiliation{},
         &models.Specialization{},
+        &models.SigningKey{},
+        &models.SecurityEvent{},
+        &models.AuditEvent{},
+        &models.ArchivedAuditEvent{},
+        &models.Session{},
+        &models.Revocation{},
+        &models.RecoveryCode{},
+        &models.LoginAttempt{},
+        &models.PasswordHistory{},
+        &models.PasswordResetToken{},
+        &models.InviteToken{},
+        &models.ContactVerification{},
+        &models.OAuthClient{},
+        &models.AuthorizationCode{},
+        &models.OAuthConsent{},
     )
+    utils.InitKeyrings(config.DB)
+
+    if len(os.Args) > 1 {
+        runCommand(os.Args[1:])
+        return
+    }
+
+    notifier.Init()
+    jobs.StartTokenSweeper()
+    jobs.StartAccountStatusScheduler()
+    jobs.StartAuditRetention()
+
     gin.SetMode(gin.DebugMode) 
     r := gin.Default()
     r.Use(gin.Logger())
     routes.InitAuthRoutes(r)
     routes.InitAccountRoutes(r)
     routes.InitDoctorRoutes(r)
+    routes.InitSpecializationRoutes(r)
+    routes.InitKeyRoutes(r)
+    routes.InitRoleRoutes(r)
+    routes.InitAuditRoutes(r)
+    routes.InitWellKnownRoutes(r)
+    routes.InitOIDCRoutes(r)
 
     r.Run(":8080")
 }
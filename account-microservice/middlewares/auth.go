@@ -1,66 +1,202 @@
 package middlewares
 
 import (
+	"log"
 	"net/http"
-	"os"
 	"strings"
 
+	"account-microservice/services"
+	"account-microservice/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 func JWTAuthMiddleware() gin.HandlerFunc {
     return func(c *gin.Context) {
-        authHeader := c.GetHeader("Authorization")
-        if authHeader == "" {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+        claims, ok := authenticate(c)
+        if !ok {
             return
         }
 
-        tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-        token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-            return []byte(os.Getenv("ACCESS_TOKEN_SECRET")), nil
-        })
+        if _, ok := claims["account_id"].(float64); !ok {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User token required"})
+            return
+        }
 
-        if err != nil || !token.Valid {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+        if !checkImpersonation(c, claims) {
             return
         }
 
-        claims, ok := token.Claims.(jwt.MapClaims)
+        setUserClaims(c, claims)
+        c.Next()
+    }
+}
+
+// UserOrServiceMiddleware accepts user tokens as well as service tokens
+// issued through the client_credentials grant that carry the given scope.
+func UserOrServiceMiddleware(scope string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        claims, ok := authenticate(c)
         if !ok {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
             return
         }
 
-        c.Set("account_id", uint(claims["account_id"].(float64)))
-        c.Set("roles", claims["roles"])
+        if _, ok := claims["account_id"].(float64); ok {
+            if !checkImpersonation(c, claims) {
+                return
+            }
+            setUserClaims(c, claims)
+            c.Next()
+            return
+        }
+
+        if !setServiceClaims(c, claims, scope) {
+            return
+        }
         c.Next()
     }
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// ServiceMiddleware accepts only service tokens that carry the given scope.
+func ServiceMiddleware(scope string) gin.HandlerFunc {
     return func(c *gin.Context) {
-        roles, exists := c.Get("roles")
-        if !exists {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Roles not found"})
+        claims, ok := authenticate(c)
+        if !ok {
             return
         }
 
-        rolesSlice := roles.([]interface{})
-        isAdmin := false
-        for _, role := range rolesSlice {
-            if role == "admin" {
-                isAdmin = true
-                break
-            }
+        if _, ok := claims["account_id"].(float64); ok {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Service token required"})
+            return
+        }
+
+        if !setServiceClaims(c, claims, scope) {
+            return
+        }
+        c.Next()
+    }
+}
+
+func setServiceClaims(c *gin.Context, claims jwt.MapClaims, scope string) bool {
+    clientID, _ := claims["client_id"].(string)
+    tokenScope, _ := claims["scope"].(string)
+    scopes := strings.Fields(tokenScope)
+    if clientID == "" || !utils.ContainsString(scopes, scope) {
+        c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Scope " + scope + " required"})
+        return false
+    }
+
+    c.Set("claims", claims)
+    c.Set("service", true)
+    c.Set("client_id", clientID)
+    c.Set("scopes", scopes)
+    return true
+}
+
+func authenticate(c *gin.Context) (jwt.MapClaims, bool) {
+    authHeader := c.GetHeader("Authorization")
+    if authHeader == "" {
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+        return nil, false
+    }
+
+    tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+    token, err := utils.ValidateAccessToken(tokenString)
+
+    if err != nil || !token.Valid {
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+        return nil, false
+    }
+
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok {
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+        return nil, false
+    }
+
+    revoked, err := services.IsRevoked(claims)
+    if err != nil {
+        c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token revocation"})
+        return nil, false
+    }
+    if revoked {
+        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+        return nil, false
+    }
+
+    return claims, true
+}
+
+func setUserClaims(c *gin.Context, claims jwt.MapClaims) {
+    c.Set("claims", claims)
+    c.Set("account_id", uint(claims["account_id"].(float64)))
+    c.Set("roles", claims["roles"])
+    c.Set("permissions", claimStrings(claims["permissions"]))
+    if sessionID, ok := claims["sid"].(float64); ok {
+        c.Set("session_id", uint(sessionID))
+    }
+    if actor, ok := claims["act"].(map[string]interface{}); ok {
+        if impersonatorID, ok := actor["account_id"].(float64); ok {
+            c.Set("impersonator_id", uint(impersonatorID))
         }
+    }
+}
+
+// checkImpersonation logs requests made with an impersonation token and
+// rejects writes when the token is read-only. Signing out stays allowed so
+// that the token can be revoked early.
+func checkImpersonation(c *gin.Context, claims jwt.MapClaims) bool {
+    actor, ok := claims["act"].(map[string]interface{})
+    if !ok {
+        return true
+    }
 
-        if !isAdmin {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin privileges required"})
+    log.Printf("Impersonated request %s %s: account %v acting as account %v",
+        c.Request.Method, c.Request.URL.Path, actor["account_id"], claims["account_id"])
+
+    readOnly, _ := claims["read_only"].(bool)
+    switch c.Request.Method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions:
+    default:
+        if readOnly && c.FullPath() != "/api/Authentication/SignOut" {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Impersonation token is read-only"})
+            return false
+        }
+    }
+    return true
+}
+
+func claimStrings(value interface{}) []string {
+    items, _ := value.([]interface{})
+    values := make([]string, 0, len(items))
+    for _, item := range items {
+        if s, ok := item.(string); ok {
+            values = append(values, s)
+        }
+    }
+    return values
+}
+
+// PermissionMiddleware requires the user token to carry the permission. It
+// lets service tokens through, since UserOrServiceMiddleware has already
+// checked their scope.
+func PermissionMiddleware(permission string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if c.GetBool("service") {
+            c.Next()
+            return
+        }
+
+        if !HasPermission(c, permission) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission " + permission + " required"})
             return
         }
 
         c.Next()
     }
 }
+
+func HasPermission(c *gin.Context, permission string) bool {
+    return utils.ContainsString(c.GetStringSlice("permissions"), permission)
+}
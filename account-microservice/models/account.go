@@ -11,10 +11,45 @@ type Account struct {
     LastName  string     `json:"lastName"`
     FirstName string     `json:"firstName"`
     Username  string     `gorm:"unique;not null" json:"username"`
+    Email     string     `gorm:"uniqueIndex:idx_accounts_email,where:email <> ''" json:"email"`
+    Phone     string     `gorm:"uniqueIndex:idx_accounts_phone,where:phone <> ''" json:"phone"`
+    EmailVerified bool   `json:"emailVerified"`
+    PhoneVerified bool   `json:"phoneVerified"`
+    Unverified    bool   `json:"unverified"`
     Password  string     `json:"-"`
+    MustChangePassword bool `json:"mustChangePassword"`
+    TOTPSecret   string  `json:"-"`
+    TOTPEnabled  bool    `json:"totpEnabled"`
+    TOTPLastStep int64   `json:"-"`
+    Status       string  `gorm:"not null;default:active;index" json:"status"`
+    StatusReason string  `json:"statusReason,omitempty"`
+    StatusEffectiveAt *time.Time `json:"statusEffectiveAt,omitempty"`
+    AppliedStatus string `gorm:"not null;default:active" json:"-"`
     Roles     []*Role    `gorm:"many2many:account_roles;constraint:OnDelete:CASCADE;" json:"roles"`
     Specializations []*Specialization `gorm:"many2many:doctor_specializations;" json:"specializations,omitempty"`
     CreatedAt time.Time  `json:"-"`
     UpdatedAt time.Time  `json:"-"`
     DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+const (
+    AccountActive    = "active"
+    AccountSuspended = "suspended"
+    AccountPending   = "pending"
+)
+
+// EffectiveStatus returns the status in force at the given time. A status
+// with a future effective date leaves the previously applied one in force
+// until then.
+func (a Account) EffectiveStatus(now time.Time) string {
+    if a.StatusEffectiveAt != nil && now.Before(*a.StatusEffectiveAt) {
+        if a.AppliedStatus == "" {
+            return AccountActive
+        }
+        return a.AppliedStatus
+    }
+    if a.Status == "" {
+        return AccountActive
+    }
+    return a.Status
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuditSignIn             = "sign_in"
+	AuditSignInFailed       = "sign_in_failed"
+	AuditTokenRefreshed     = "token_refreshed"
+	AuditRefreshTokenReused = "refresh_token_reused"
+	AuditAccountUpdated     = "account_updated"
+	AuditAccountDeleted     = "account_deleted"
+)
+
+var ErrAuditEventImmutable = errors.New("audit events are append-only")
+
+// AuditEvent records who did what to which account. Before and After hold
+// only the fields the action changed. Events are never updated; the
+// retention job moves old ones to ArchivedAuditEvent.
+type AuditEvent struct {
+	ID        uint                   `gorm:"primaryKey" json:"id"`
+	Action    string                 `gorm:"index;not null" json:"action"`
+	ActorID   uint                   `gorm:"index" json:"actorId,omitempty"`
+	TargetID  uint                   `gorm:"index" json:"targetId,omitempty"`
+	IP        string                 `gorm:"index" json:"ip"`
+	UserAgent string                 `json:"userAgent"`
+	Before    map[string]interface{} `gorm:"serializer:json" json:"before,omitempty"`
+	After     map[string]interface{} `gorm:"serializer:json" json:"after,omitempty"`
+	CreatedAt time.Time              `gorm:"index" json:"createdAt"`
+}
+
+func (AuditEvent) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+func (AuditEvent) BeforeDelete(*gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+// ArchivedAuditEvent is an audit event past the retention period.
+type ArchivedAuditEvent struct {
+	ID         uint                   `gorm:"primaryKey" json:"id"`
+	Action     string                 `gorm:"index;not null" json:"action"`
+	ActorID    uint                   `gorm:"index" json:"actorId,omitempty"`
+	TargetID   uint                   `gorm:"index" json:"targetId,omitempty"`
+	IP         string                 `gorm:"index" json:"ip"`
+	UserAgent  string                 `json:"userAgent"`
+	Before     map[string]interface{} `gorm:"serializer:json" json:"before,omitempty"`
+	After      map[string]interface{} `gorm:"serializer:json" json:"after,omitempty"`
+	CreatedAt  time.Time              `gorm:"index" json:"createdAt"`
+	ArchivedAt time.Time              `json:"archivedAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type AuthorizationCode struct {
+	ID                  uint   `gorm:"primaryKey"`
+	CodeHash            string `gorm:"unique;not null"`
+	ClientID            string `gorm:"index;not null"`
+	AccountID           uint   `gorm:"not null"`
+	RedirectURI         string `gorm:"not null"`
+	RedirectURIProvided bool   `gorm:"not null;default:true"`
+	Scope               string
+	Nonce               string
+	CodeChallenge       string `gorm:"not null"`
+	CodeChallengeMethod string `gorm:"not null"`
+	AuthTime            time.Time
+	ExpiresAt           time.Time `gorm:"index"`
+	UsedAt              *time.Time
+	SessionID           uint
+	CreatedAt           time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	ContactEmail = "email"
+	ContactPhone = "phone"
+)
+
+type ContactVerification struct {
+	ID          uint      `gorm:"primaryKey"`
+	AccountID   uint      `gorm:"index;not null"`
+	Channel     string    `gorm:"not null"`
+	Destination string    `gorm:"not null"`
+	CodeHash    string    `gorm:"not null"`
+	Attempts    int       `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
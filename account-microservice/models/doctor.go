@@ -1,8 +1,36 @@
 package models
 
-type Doctor struct {
-	ID             uint   `gorm:"primaryKey" json:"id"`
-	LastName       string `json:"lastName"`
-	FirstName      string `json:"firstName"`
-	Specialization string `json:"specialization"`
+import "time"
+
+// DoctorProfile holds the public-facing details of an account with the
+// doctor role. Only public profiles are shown to patients.
+type DoctorProfile struct {
+	AccountID         uint       `gorm:"primaryKey" json:"-"`
+	Biography         string     `json:"biography"`
+	LicenceNumber     string     `gorm:"uniqueIndex:idx_doctor_profiles_licence,where:licence_number <> ''" json:"licenceNumber"`
+	LicenceExpiresAt  *time.Time `json:"licenceExpiresAt"`
+	PracticeStartYear int        `json:"practiceStartYear"`
+	Languages         []string   `gorm:"serializer:json" json:"languages"`
+	AcademicDegree    string     `json:"academicDegree"`
+	Public            bool       `gorm:"not null;default:false" json:"public"`
+	HasPhoto          bool       `json:"-"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// YearsOfExperience counts full calendar years since the doctor started
+// practising.
+func (p DoctorProfile) YearsOfExperience(now time.Time) int {
+	if p.PracticeStartYear == 0 || p.PracticeStartYear > now.Year() {
+		return 0
+	}
+	return now.Year() - p.PracticeStartYear
+}
+
+// DoctorPhoto is kept apart from the profile so that listing profiles does
+// not load image data.
+type DoctorPhoto struct {
+	AccountID   uint   `gorm:"primaryKey"`
+	ContentType string `gorm:"not null"`
+	Data        []byte `gorm:"not null"`
+	UpdatedAt   time.Time
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	GuardianshipPending = "pending"
+	GuardianshipActive  = "active"
+	GuardianshipRevoked = "revoked"
+)
+
+// Guardianship lets the guardian act on behalf of the dependent once the
+// dependent, or an administrator, has consented. Links may expire.
+type Guardianship struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	GuardianID    uint       `gorm:"index;not null" json:"guardianId"`
+	DependentID   uint       `gorm:"index;not null" json:"dependentId"`
+	Relationship  string     `json:"relationship"`
+	Status        string     `gorm:"index;not null" json:"status"`
+	ConsentedByID uint       `json:"consentedById,omitempty"`
+	ConsentedAt   *time.Time `json:"consentedAt"`
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// IsActive reports whether the guardian may currently act for the dependent.
+func (g Guardianship) IsActive(now time.Time) bool {
+	return g.Status == GuardianshipActive && (g.ExpiresAt == nil || g.ExpiresAt.After(now))
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// HospitalAffiliation links a staff account to a hospital with a
+// hospital-scoped role. An account has at most one role per hospital.
+type HospitalAffiliation struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	AccountID  uint      `gorm:"uniqueIndex:idx_hospital_affiliations_account_hospital;not null" json:"accountId"`
+	HospitalID uint      `gorm:"uniqueIndex:idx_hospital_affiliations_account_hospital;index;not null" json:"hospitalId"`
+	RoleID     uint      `gorm:"not null" json:"-"`
+	Role       *Role     `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// InviteToken lets an imported account choose its first password. Only the
+// hash of the token is stored.
+type InviteToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	AccountID uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"unique;not null"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt counts recent failed sign-ins for a key of the form
+// "user:<username>" or "ip:<address>".
+type LoginAttempt struct {
+	Key           string     `gorm:"primaryKey" json:"key"`
+	Failures      int        `gorm:"not null" json:"failures"`
+	LastFailureAt time.Time  `gorm:"index" json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+}
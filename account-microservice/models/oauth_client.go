@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type OAuthClient struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	ClientID     string    `gorm:"unique;not null" json:"clientId"`
+	SecretHash   string    `json:"-"`
+	Name         string    `gorm:"not null" json:"name"`
+	RedirectURIs []string  `gorm:"serializer:json" json:"redirectUris"`
+	Scopes       []string  `gorm:"serializer:json" json:"scopes"`
+	GrantTypes   []string  `gorm:"serializer:json" json:"grantTypes"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+var DefaultGrantTypes = []string{"authorization_code", "refresh_token"}
+
+func (c OAuthClient) AllowsGrant(grantType string) bool {
+	grantTypes := c.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = DefaultGrantTypes
+	}
+	for _, allowed := range grantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+	return false
+}
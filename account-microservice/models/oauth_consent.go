@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type OAuthConsent struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	AccountID uint      `gorm:"uniqueIndex:idx_oauth_consent;not null" json:"-"`
+	ClientID  string    `gorm:"uniqueIndex:idx_oauth_consent;not null" json:"clientId"`
+	Scopes    []string  `gorm:"serializer:json" json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type PasswordHistory struct {
+	ID           uint   `gorm:"primaryKey"`
+	AccountID    uint   `gorm:"index;not null"`
+	PasswordHash string `gorm:"not null"`
+	CreatedAt    time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	AccountID uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"unique;not null"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
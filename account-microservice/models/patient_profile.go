@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	SexMale   = "male"
+	SexFemale = "female"
+)
+
+// PatientProfile holds the demographic and insurance details a clinic needs
+// to register a patient. SNILS and OMS are stored as digits only.
+type PatientProfile struct {
+	AccountID             uint       `gorm:"primaryKey" json:"accountId"`
+	Patronymic            string     `json:"patronymic"`
+	BirthDate             *time.Time `gorm:"type:date" json:"birthDate"`
+	Sex                   string     `json:"sex"`
+	OMSNumber             string     `gorm:"uniqueIndex:idx_patient_profiles_oms,where:oms_number <> ''" json:"omsNumber"`
+	SNILS                 string     `gorm:"column:snils;uniqueIndex:idx_patient_profiles_snils,where:snils <> ''" json:"snils"`
+	Address               string     `json:"address"`
+	EmergencyContactName  string     `json:"emergencyContactName"`
+	EmergencyContactPhone string     `json:"emergencyContactPhone"`
+	UpdatedAt             time.Time  `json:"updatedAt"`
+}
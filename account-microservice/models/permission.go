@@ -0,0 +1,94 @@
+package models
+
+const (
+	PermissionAccountRead         = "account.read"
+	PermissionAccountWrite        = "account.write"
+	PermissionDoctorWrite         = "doctor.write"
+	PermissionSpecializationWrite = "specialization.write"
+	PermissionRoleWrite           = "role.write"
+	PermissionKeyWrite            = "key.write"
+	PermissionClientWrite         = "client.write"
+	PermissionHospitalWrite       = "hospital.write"
+	PermissionTimetableWrite      = "timetable.write"
+	PermissionTimetableRoomRead   = "timetable.room.read"
+	PermissionAppointmentWrite    = "appointment.write"
+	PermissionHistoryRead         = "history.read"
+	PermissionHistoryWrite        = "history.write"
+	PermissionHistoryOwn          = "history.own"
+	PermissionPatientRead         = "patient.read"
+	PermissionAccountImpersonate  = "account.impersonate"
+	PermissionAuditRead           = "audit.read"
+)
+
+// Permission is a single capability checked by one of the services. Roles
+// grant permissions and access tokens carry the union of them.
+type Permission struct {
+	ID          uint    `gorm:"primaryKey" json:"-"`
+	Name        string  `gorm:"unique;not null" json:"name"`
+	Description string  `json:"description"`
+	Roles       []*Role `gorm:"many2many:role_permissions;" json:"-"`
+}
+
+var Permissions = []Permission{
+	{Name: PermissionAccountRead, Description: "View accounts, their sessions and lockouts"},
+	{Name: PermissionAccountWrite, Description: "Create, update and delete accounts, revoke sessions, reset 2FA and lockouts"},
+	{Name: PermissionDoctorWrite, Description: "Register doctors"},
+	{Name: PermissionSpecializationWrite, Description: "Manage the specialization catalogue and doctors' specializations"},
+	{Name: PermissionRoleWrite, Description: "Manage roles and their policies"},
+	{Name: PermissionKeyWrite, Description: "View and rotate token signing keys"},
+	{Name: PermissionClientWrite, Description: "Register and delete OAuth clients"},
+	{Name: PermissionHospitalWrite, Description: "Create, update and delete hospitals"},
+	{Name: PermissionTimetableWrite, Description: "Create, update and delete timetables"},
+	{Name: PermissionTimetableRoomRead, Description: "View the timetable of a hospital room"},
+	{Name: PermissionAppointmentWrite, Description: "Cancel appointments of other accounts"},
+	{Name: PermissionHistoryRead, Description: "View the medical history of any patient"},
+	{Name: PermissionHistoryWrite, Description: "Create and update medical history records"},
+	{Name: PermissionHistoryOwn, Description: "Be the patient of medical history records"},
+	{Name: PermissionPatientRead, Description: "View patient profiles and search patients by SNILS or OMS policy"},
+	{Name: PermissionAccountImpersonate, Description: "Obtain short-lived tokens acting as another account"},
+	{Name: PermissionAuditRead, Description: "View the audit log of sign-ins, token refreshes and account changes"},
+}
+
+// HospitalPermissions apply to a single hospital's data. Hospital-scoped roles
+// grant them per hospital through affiliations rather than globally.
+var HospitalPermissions = []string{
+	PermissionHospitalWrite, PermissionTimetableWrite, PermissionTimetableRoomRead,
+	PermissionAppointmentWrite, PermissionHistoryRead, PermissionHistoryWrite,
+}
+
+// IsHospitalPermission reports whether the permission can be granted per
+// hospital.
+func IsHospitalPermission(name string) bool {
+	for _, permission := range HospitalPermissions {
+		if permission == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HospitalScopedRoles are the built-in roles whose staff work in specific
+// hospitals.
+var HospitalScopedRoles = []string{"manager", "doctor"}
+
+// DefaultRolePermissions is granted to the built-in roles when they have no
+// permissions yet.
+var DefaultRolePermissions = map[string][]string{
+	"admin": {
+		PermissionAccountRead, PermissionAccountWrite, PermissionDoctorWrite,
+		PermissionSpecializationWrite, PermissionRoleWrite, PermissionKeyWrite, PermissionClientWrite,
+		PermissionHospitalWrite, PermissionTimetableWrite, PermissionTimetableRoomRead,
+		PermissionAppointmentWrite, PermissionHistoryWrite, PermissionPatientRead, PermissionAccountImpersonate,
+		PermissionAuditRead,
+	},
+	"manager": {
+		PermissionSpecializationWrite, PermissionTimetableWrite, PermissionTimetableRoomRead,
+		PermissionAppointmentWrite, PermissionHistoryWrite, PermissionPatientRead,
+	},
+	"doctor": {
+		PermissionTimetableRoomRead, PermissionHistoryRead, PermissionHistoryWrite, PermissionPatientRead,
+	},
+	"user": {
+		PermissionHistoryOwn,
+	},
+}
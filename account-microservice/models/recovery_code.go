@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	AccountID uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
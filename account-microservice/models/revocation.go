@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	RevocationKindToken   = "jti"
+	RevocationKindAccount = "account"
+	RevocationKindSession = "session"
+	RevocationKindClient  = "client"
+)
+
+// Revocation denies a single access token by jti, or every access token of
+// an account, session or OAuth client issued before RevokedAt.
+type Revocation struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	Kind      string    `gorm:"index:idx_revocation_lookup;not null" json:"kind"`
+	Value     string    `gorm:"index:idx_revocation_lookup;not null" json:"value"`
+	Reason    string    `json:"reason,omitempty"`
+	RevokedAt time.Time `json:"revokedAt"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+}
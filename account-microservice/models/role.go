@@ -1,7 +1,20 @@
 package models
 
+import "strings"
+
+// Role grants permissions to the accounts holding it. Hospital-scoped roles
+// grant their hospital permissions only in the hospitals where the account
+// is affiliated with the role.
 type Role struct {
-	ID       uint       `gorm:"primaryKey" json:"-"`
-	Name     string     `gorm:"unique;not null" json:"name"`
-	Accounts []*Account `gorm:"many2many:account_roles;" json:"-"`
+	ID             uint          `gorm:"primaryKey" json:"-"`
+	Name           string        `gorm:"unique;not null" json:"name"`
+	MFARequired    bool          `json:"mfaRequired"`
+	HospitalScoped bool          `gorm:"not null;default:false" json:"hospitalScoped"`
+	Permissions    []*Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+	Accounts       []*Account    `gorm:"many2many:account_roles;" json:"-"`
+}
+
+// NormalizeRoleName returns the canonical, lowercase form of a role name.
+func NormalizeRoleName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
 }
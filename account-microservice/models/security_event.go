@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	SecurityEventAccountLocked     = "account_locked"
+	SecurityEventAccountUnlocked   = "account_unlocked"
+	SecurityEventPasswordReset     = "password_reset"
+	SecurityEventRoleAssigned      = "role_assigned"
+	SecurityEventRoleRemoved       = "role_removed"
+	SecurityEventRoleCreated       = "role_created"
+	SecurityEventRoleUpdated       = "role_updated"
+	SecurityEventRoleDeleted       = "role_deleted"
+	SecurityEventGuardianRequested = "guardian_requested"
+	SecurityEventGuardianConsented = "guardian_consented"
+	SecurityEventGuardianRevoked   = "guardian_revoked"
+	SecurityEventStatusChanged     = "account_status_changed"
+	SecurityEventAccountsExported  = "accounts_exported"
+	SecurityEventAccountsImported  = "accounts_imported"
+	SecurityEventInviteAccepted    = "invite_accepted"
+	SecurityEventImpersonation     = "impersonation_started"
+)
+
+type SecurityEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	AccountID uint      `gorm:"index" json:"accountId"`
+	ActorID   uint      `gorm:"index" json:"actorId,omitempty"`
+	Type      string    `gorm:"index;not null" json:"type"`
+	Details   string    `json:"details"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	AccountID  uint       `gorm:"index;not null" json:"accountId"`
+	FamilyID   string     `gorm:"unique;not null" json:"-"`
+	ClientID   string     `json:"clientId,omitempty"`
+	Scope      string     `json:"scope,omitempty"`
+	DeviceName string     `json:"deviceName"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
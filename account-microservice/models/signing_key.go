@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type SigningKey struct {
+	ID        uint       `gorm:"primaryKey" json:"-"`
+	Kid       string     `gorm:"unique;not null" json:"kid"`
+	Use       string     `gorm:"index;not null" json:"use"`
+	Algorithm string     `gorm:"not null" json:"algorithm"`
+	Material  string     `gorm:"not null" json:"-"`
+	Active    bool       `gorm:"index" json:"active"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
@@ -3,8 +3,12 @@ package models
 import "time"
 
 type Token struct {
-    ID        uint      `gorm:"primaryKey" json:"-"`
-    Token     string    `gorm:"unique;not null" json:"token"`
-    AccountID uint      `json:"account_id"`
-    ExpiresAt time.Time `json:"expires_at"`
+    ID        uint       `gorm:"primaryKey" json:"-"`
+    Token     string     `gorm:"unique;not null" json:"token"`
+    AccountID uint       `json:"account_id"`
+    FamilyID  string     `gorm:"index" json:"family_id"`
+    ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+    RotatedAt *time.Time `json:"rotated_at,omitempty"`
+    RevokedAt *time.Time `json:"revoked_at,omitempty"`
+    CreatedAt time.Time  `json:"created_at"`
 }
@@ -0,0 +1,36 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type LogNotifier struct{}
+
+func (LogNotifier) Send(message Message) error {
+	log.Printf("Notification to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+var fileMutex sync.Mutex
+
+type FileNotifier struct {
+	Path string
+}
+
+func (n FileNotifier) Send(message Message) error {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	file, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), message.To, message.Subject, message.Body)
+	return err
+}
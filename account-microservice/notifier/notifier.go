@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+type Message struct {
+	Channel string
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Send(message Message) error
+}
+
+var (
+	Email Notifier = LogNotifier{}
+	SMS   Notifier = LogNotifier{}
+)
+
+// Init selects the email notifier from NOTIFIER ("smtp", "file" or "log")
+// and the SMS notifier from SMS_NOTIFIER ("webhook", "file" or "log").
+func Init() {
+	Email = fromEnv("NOTIFIER")
+	SMS = fromEnv("SMS_NOTIFIER")
+}
+
+func Send(message Message) error {
+	if message.Channel == ChannelSMS {
+		return SMS.Send(message)
+	}
+	return Email.Send(message)
+}
+
+func fromEnv(key string) Notifier {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "smtp":
+		return NewSMTPNotifierFromEnv()
+	case "webhook":
+		return NewWebhookNotifierFromEnv()
+	case "file":
+		path := os.Getenv(key + "_FILE")
+		if path == "" {
+			path = "notifications.log"
+		}
+		return FileNotifier{Path: path}
+	case "", "log":
+		return LogNotifier{}
+	default:
+		log.Printf("Unknown %s %q, falling back to log", key, os.Getenv(key))
+		return LogNotifier{}
+	}
+}
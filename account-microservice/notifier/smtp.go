@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPNotifierFromEnv() SMTPNotifier {
+	notifier := SMTPNotifier{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if notifier.Port == "" {
+		notifier.Port = "25"
+	}
+	if notifier.From == "" {
+		notifier.From = "no-reply@volga.local"
+	}
+	return notifier
+}
+
+func (n SMTPNotifier) Send(message Message) error {
+	if n.Host == "" {
+		return fmt.Errorf("SMTP_HOST is not configured")
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	headers := []string{
+		"From: " + n.From,
+		"To: " + message.To,
+		"Subject: " + message.Subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(message.Body, "\n", "\r\n")
+
+	return smtp.SendMail(net.JoinHostPort(n.Host, n.Port), auth, n.From, []string{message.To}, []byte(body))
+}
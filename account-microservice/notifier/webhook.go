@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// WebhookNotifier posts messages as JSON to an HTTP gateway, typically an
+// SMS provider adapter.
+type WebhookNotifier struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func NewWebhookNotifierFromEnv() WebhookNotifier {
+	return WebhookNotifier{
+		URL:    os.Getenv("SMS_WEBHOOK_URL"),
+		Token:  os.Getenv("SMS_WEBHOOK_TOKEN"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n WebhookNotifier) Send(message Message) error {
+	if n.URL == "" {
+		return fmt.Errorf("SMS_WEBHOOK_URL is not configured")
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"to":      message.To,
+		"subject": message.Subject,
+		"body":    message.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if n.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("SMS gateway responded with %s", response.Status)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"account-microservice/controllers"
 	"account-microservice/middlewares"
+	"account-microservice/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,11 +13,47 @@ func InitAccountRoutes(r *gin.Engine) {
     {
         accountRoutes.GET("/Me", middlewares.JWTAuthMiddleware(), controllers.GetCurrentAccount)
         accountRoutes.PUT("/Update", middlewares.JWTAuthMiddleware(), controllers.UpdateCurrentAccount)
-        accountRoutes.GET("/", middlewares.JWTAuthMiddleware(), middlewares.AdminMiddleware(), controllers.GetAllAccounts)
-        accountRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.AdminMiddleware(), controllers.CreateAccount)
-        accountRoutes.PUT("/:id", middlewares.JWTAuthMiddleware(), middlewares.AdminMiddleware(), controllers.UpdateAccount)
-        accountRoutes.DELETE("/:id", middlewares.JWTAuthMiddleware(), middlewares.AdminMiddleware(), controllers.DeleteAccount)
-        accountRoutes.GET("/:id/roles", middlewares.JWTAuthMiddleware(), controllers.CheckUserRole)
+        accountRoutes.GET("/Me/Sessions", middlewares.JWTAuthMiddleware(), controllers.GetCurrentSessions)
+        accountRoutes.DELETE("/Me/Sessions/:sessionId", middlewares.JWTAuthMiddleware(), controllers.RevokeCurrentSession)
+        accountRoutes.POST("/Me/TOTP", middlewares.JWTAuthMiddleware(), controllers.BeginTOTPEnrollment)
+        accountRoutes.POST("/Me/TOTP/Confirm", middlewares.JWTAuthMiddleware(), controllers.ConfirmTOTPEnrollment)
+        accountRoutes.POST("/Me/TOTP/RecoveryCodes", middlewares.JWTAuthMiddleware(), controllers.RegenerateRecoveryCodes)
+        accountRoutes.DELETE("/Me/TOTP", middlewares.JWTAuthMiddleware(), controllers.DisableTOTP)
+        accountRoutes.GET("/Me/Profile", middlewares.JWTAuthMiddleware(), controllers.GetCurrentPatientProfile)
+        accountRoutes.PUT("/Me/Profile", middlewares.JWTAuthMiddleware(), controllers.UpdateCurrentPatientProfile)
+        accountRoutes.GET("/Me/Dependents", middlewares.JWTAuthMiddleware(), controllers.GetCurrentDependents)
+        accountRoutes.POST("/Me/Dependents", middlewares.JWTAuthMiddleware(), controllers.RequestDependent)
+        accountRoutes.DELETE("/Me/Dependents/:linkId", middlewares.JWTAuthMiddleware(), controllers.RemoveDependent)
+        accountRoutes.GET("/Me/Guardians", middlewares.JWTAuthMiddleware(), controllers.GetCurrentGuardians)
+        accountRoutes.POST("/Me/Guardians/:linkId/Consent", middlewares.JWTAuthMiddleware(), controllers.ConsentGuardian)
+        accountRoutes.DELETE("/Me/Guardians/:linkId", middlewares.JWTAuthMiddleware(), controllers.RevokeGuardian)
+        accountRoutes.GET("/Me/Hospitals", middlewares.JWTAuthMiddleware(), controllers.GetCurrentHospitals)
+        accountRoutes.POST("/Me/Verification", middlewares.JWTAuthMiddleware(), controllers.SendContactVerification)
+        accountRoutes.POST("/Me/Verification/Confirm", middlewares.JWTAuthMiddleware(), controllers.ConfirmContactVerification)
+        accountRoutes.GET("/", middlewares.UserOrServiceMiddleware("accounts:read"), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.GetAllAccounts)
+        accountRoutes.POST("/Import", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.ImportAccounts)
+        accountRoutes.GET("/Search", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.SearchAccounts)
+        accountRoutes.GET("/Export", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.ExportAccounts)
+        accountRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.CreateAccount)
+        accountRoutes.PUT("/:id", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.UpdateAccount)
+        accountRoutes.DELETE("/:id", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.DeleteAccount)
+        accountRoutes.POST("/:id/Impersonate", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountImpersonate), controllers.ImpersonateAccount)
+        accountRoutes.PUT("/:id/Status", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.UpdateAccountStatus)
+        accountRoutes.GET("/:id/roles", middlewares.UserOrServiceMiddleware("accounts:read"), controllers.CheckUserRole)
+        accountRoutes.GET("/:id/Sessions", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.GetAccountSessions)
+        accountRoutes.DELETE("/:id/Sessions/:sessionId", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.RevokeAccountSession)
+        accountRoutes.DELETE("/:id/TOTP", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.ResetAccountTOTP)
+        accountRoutes.GET("/:id/Hospitals", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.GetAccountHospitals)
+        accountRoutes.PUT("/:id/Hospitals", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.UpdateAccountHospitals)
+        accountRoutes.GET("/Guardianships", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.GetGuardianships)
+        accountRoutes.POST("/Guardianships", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.CreateGuardianship)
+        accountRoutes.DELETE("/Guardianships/:linkId", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.DeleteGuardianship)
+        accountRoutes.GET("/Profiles/Search", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionPatientRead), controllers.SearchPatients)
+        accountRoutes.GET("/:id/Profile", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionPatientRead), controllers.GetPatientProfile)
+        accountRoutes.PUT("/:id/Profile", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.UpdatePatientProfile)
+        accountRoutes.GET("/Lockouts", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.GetLockouts)
+        accountRoutes.GET("/:id/Lockout", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountRead), controllers.GetAccountLockout)
+        accountRoutes.DELETE("/:id/Lockout", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAccountWrite), controllers.UnlockAccount)
 
     }
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"account-microservice/controllers"
+	"account-microservice/middlewares"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitAuditRoutes(r *gin.Engine) {
+    auditRoutes := r.Group("/api/Audit")
+    {
+        auditRoutes.GET("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionAuditRead), controllers.GetAuditEvents)
+    }
+}
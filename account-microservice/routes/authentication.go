@@ -12,8 +12,15 @@ func InitAuthRoutes(r *gin.Engine) {
     {
         authRoutes.POST("/SignUp", controllers.SignUp)
         authRoutes.POST("/SignIn", controllers.SignIn)
+        authRoutes.POST("/SignIn/Enroll", controllers.EnrollMFAChallenge)
+        authRoutes.POST("/SignIn/Verify", controllers.VerifyMFAChallenge)
+        authRoutes.POST("/SignIn/ChangePassword", controllers.ChangePasswordChallenge)
         authRoutes.PUT("/SignOut", middlewares.JWTAuthMiddleware(), controllers.SignOut)
         authRoutes.GET("/Validate", controllers.ValidateToken)
+        authRoutes.GET("/Revocations", middlewares.ServiceMiddleware("revocations:read"), controllers.GetRevocations)
         authRoutes.POST("/Refresh", controllers.RefreshToken)
+        authRoutes.POST("/PasswordReset", controllers.RequestPasswordReset)
+        authRoutes.POST("/PasswordReset/Confirm", controllers.ConfirmPasswordReset)
+        authRoutes.POST("/Invite/Accept", controllers.AcceptInvite)
     }
 }
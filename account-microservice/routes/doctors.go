@@ -3,6 +3,7 @@ package routes
 import (
 	"account-microservice/controllers"
 	"account-microservice/middlewares"
+	"account-microservice/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,8 +11,14 @@ import (
 func InitDoctorRoutes(r *gin.Engine) {
     doctorRoutes := r.Group("/api/Doctors")
     {
-        doctorRoutes.GET("/", middlewares.JWTAuthMiddleware(), controllers.GetDoctors)
-        doctorRoutes.GET("/:id", middlewares.JWTAuthMiddleware(), controllers.GetDoctorByID)
-        doctorRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.AdminMiddleware(), controllers.CreateDoctor)
+        doctorRoutes.GET("/", middlewares.UserOrServiceMiddleware("accounts:read"), controllers.GetDoctors)
+        doctorRoutes.GET("/Licences/Expiring", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionDoctorWrite), controllers.GetExpiringLicences)
+        doctorRoutes.GET("/:id", middlewares.UserOrServiceMiddleware("accounts:read"), controllers.GetDoctorByID)
+        doctorRoutes.GET("/:id/Photo", middlewares.UserOrServiceMiddleware("accounts:read"), controllers.GetDoctorPhoto)
+        doctorRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionDoctorWrite), controllers.CreateDoctor)
+        doctorRoutes.PUT("/:id/Profile", middlewares.JWTAuthMiddleware(), controllers.UpdateDoctorProfile)
+        doctorRoutes.PUT("/:id/Photo", middlewares.JWTAuthMiddleware(), controllers.UploadDoctorPhoto)
+        doctorRoutes.DELETE("/:id/Photo", middlewares.JWTAuthMiddleware(), controllers.DeleteDoctorPhoto)
+        doctorRoutes.PUT("/:id/Specializations", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionSpecializationWrite), controllers.UpdateDoctorSpecializations)
     }
 }
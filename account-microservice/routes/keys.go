@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"account-microservice/controllers"
+	"account-microservice/middlewares"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitKeyRoutes(r *gin.Engine) {
+    keyRoutes := r.Group("/api/Keys")
+    {
+        keyRoutes.GET("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionKeyWrite), controllers.GetSigningKeys)
+        keyRoutes.POST("/Rotate", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionKeyWrite), controllers.RotateSigningKeys)
+    }
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"account-microservice/controllers"
+	"account-microservice/middlewares"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitOIDCRoutes(r *gin.Engine) {
+    oauthRoutes := r.Group("/oauth")
+    {
+        oauthRoutes.GET("/authorize", controllers.Authorize)
+        oauthRoutes.POST("/authorize", controllers.AuthorizeSubmit)
+        oauthRoutes.POST("/token", controllers.Token)
+        oauthRoutes.GET("/userinfo", middlewares.JWTAuthMiddleware(), controllers.UserInfo)
+        oauthRoutes.POST("/userinfo", middlewares.JWTAuthMiddleware(), controllers.UserInfo)
+    }
+
+    clientRoutes := r.Group("/api/OAuth/Clients")
+    {
+        clientRoutes.GET("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionClientWrite), controllers.GetOAuthClients)
+        clientRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionClientWrite), controllers.CreateOAuthClient)
+        clientRoutes.DELETE("/:clientId", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionClientWrite), controllers.DeleteOAuthClient)
+    }
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"account-microservice/controllers"
+	"account-microservice/middlewares"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoleRoutes(r *gin.Engine) {
+    roleRoutes := r.Group("/api/Roles")
+    {
+        roleRoutes.GET("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.GetRoles)
+        roleRoutes.GET("/Permissions", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.GetPermissions)
+        roleRoutes.GET("/Audit", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.GetRoleAudit)
+        roleRoutes.GET("/:name", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.GetRole)
+        roleRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.CreateRole)
+        roleRoutes.PUT("/:name", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.UpdateRole)
+        roleRoutes.DELETE("/:name", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.DeleteRole)
+        roleRoutes.PUT("/:name/Policy", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionRoleWrite), controllers.UpdateRolePolicy)
+    }
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"account-microservice/controllers"
+	"account-microservice/middlewares"
+	"account-microservice/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitSpecializationRoutes(r *gin.Engine) {
+    specializationRoutes := r.Group("/api/Specializations")
+    {
+        specializationRoutes.GET("/", middlewares.UserOrServiceMiddleware("accounts:read"), controllers.GetSpecializations)
+        specializationRoutes.GET("/:id", middlewares.UserOrServiceMiddleware("accounts:read"), controllers.GetSpecialization)
+        specializationRoutes.POST("/", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionSpecializationWrite), controllers.CreateSpecialization)
+        specializationRoutes.PUT("/:id", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionSpecializationWrite), controllers.UpdateSpecialization)
+        specializationRoutes.DELETE("/:id", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionSpecializationWrite), controllers.DeleteSpecialization)
+        specializationRoutes.POST("/:id/Merge", middlewares.JWTAuthMiddleware(), middlewares.PermissionMiddleware(models.PermissionSpecializationWrite), controllers.MergeSpecializations)
+    }
+}
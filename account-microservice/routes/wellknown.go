@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"account-microservice/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitWellKnownRoutes(r *gin.Engine) {
+    wellKnownRoutes := r.Group("/.well-known")
+    {
+        wellKnownRoutes.GET("/jwks.json", controllers.GetJWKS)
+        wellKnownRoutes.GET("/openid-configuration", controllers.GetOpenIDConfiguration)
+    }
+}
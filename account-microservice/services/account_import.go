@@ -0,0 +1,440 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/notifier"
+	"account-microservice/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CredentialsPassword = "password"
+	CredentialsInvite   = "invite"
+
+	MaxImportRows = 1000
+)
+
+var (
+	ErrNoImportRows       = errors.New("Import contains no rows")
+	ErrTooManyImportRows  = fmt.Errorf("Import is limited to %d rows", MaxImportRows)
+	ErrInvalidCredentials = errors.New("Credentials must be 'password' or 'invite'")
+)
+
+// ImportRow is a single account to create. Roles and specializations are
+// referenced by name and must already exist.
+type ImportRow struct {
+	LastName        string   `json:"lastName"`
+	FirstName       string   `json:"firstName"`
+	Username        string   `json:"username"`
+	Email           string   `json:"email"`
+	Phone           string   `json:"phone"`
+	Roles           []string `json:"roles"`
+	Specializations []string `json:"specializations"`
+}
+
+type ImportOptions struct {
+	DryRun      bool
+	Credentials string
+	ActorID     uint
+}
+
+// ImportRowResult reports the outcome of a row. Generated passwords, and
+// invite tokens for accounts without an email address, are only ever shown
+// here.
+type ImportRowResult struct {
+	Row         int      `json:"row"`
+	Username    string   `json:"username"`
+	Errors      []string `json:"errors,omitempty"`
+	AccountID   uint     `json:"accountId,omitempty"`
+	Password    string   `json:"password,omitempty"`
+	InviteToken string   `json:"inviteToken,omitempty"`
+	InviteSent  bool     `json:"inviteSent,omitempty"`
+}
+
+type ImportResult struct {
+	DryRun  bool              `json:"dryRun"`
+	Valid   bool              `json:"valid"`
+	Created int               `json:"created"`
+	Rows    []ImportRowResult `json:"rows"`
+
+	invites        []pendingInvite
+	inviteLifetime time.Duration
+}
+
+type pendingInvite struct {
+	account models.Account
+	token   string
+}
+
+// ParseImportCSV reads rows from a CSV file with a header line. Roles and
+// specializations are separated by ';' within their cells.
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrNoImportRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	cell := func(record []string, name string) string {
+		if i, ok := columns[strings.ToLower(name)]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		rows = append(rows, ImportRow{
+			LastName:        cell(record, "lastName"),
+			FirstName:       cell(record, "firstName"),
+			Username:        cell(record, "username"),
+			Email:           cell(record, "email"),
+			Phone:           cell(record, "phone"),
+			Roles:           splitList(cell(record, "roles")),
+			Specializations: splitList(cell(record, "specializations")),
+		})
+	}
+	return rows, nil
+}
+
+// ParseImportJSON reads rows from a JSON array.
+func ParseImportJSON(r io.Reader) ([]ImportRow, error) {
+	var rows []ImportRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("Invalid JSON: %v", err)
+	}
+	return rows, nil
+}
+
+// ImportAccounts validates every row and, unless it is a dry run or a row is
+// invalid, creates all accounts in one transaction.
+func ImportAccounts(rows []ImportRow, options ImportOptions) (ImportResult, error) {
+	result := ImportResult{DryRun: options.DryRun}
+	if len(rows) == 0 {
+		return result, ErrNoImportRows
+	}
+	if len(rows) > MaxImportRows {
+		return result, ErrTooManyImportRows
+	}
+	if options.Credentials != CredentialsPassword && options.Credentials != CredentialsInvite {
+		return result, ErrInvalidCredentials
+	}
+
+	accounts, err := validateImportRows(rows, &result)
+	if err != nil {
+		return result, err
+	}
+	if !result.Valid || options.DryRun {
+		return result, nil
+	}
+
+	policy := CurrentPasswordPolicy()
+	inviteLifetime := config.GetDuration("INVITE_TTL", 7*24*time.Hour)
+	inviteTokens := make([]string, len(accounts))
+
+	for i := range accounts {
+		password, err := policy.GeneratePassword(accounts[i].Username)
+		if err != nil {
+			return result, err
+		}
+		if accounts[i].Password, err = utils.HashPassword(password); err != nil {
+			return result, err
+		}
+		if options.Credentials == CredentialsPassword {
+			result.Rows[i].Password = password
+		} else if inviteTokens[i], err = utils.RandomString(32); err != nil {
+			return result, err
+		}
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range accounts {
+			account := &accounts[i]
+			if err := tx.Create(account).Error; err != nil {
+				return fmt.Errorf("row %d: %w", result.Rows[i].Row, err)
+			}
+
+			if options.Credentials == CredentialsPassword {
+				if err := RecordPasswordHistory(tx, account.ID, account.Password); err != nil {
+					return err
+				}
+			} else {
+				invite := models.InviteToken{
+					AccountID: account.ID,
+					TokenHash: utils.HashToken(inviteTokens[i]),
+					ExpiresAt: time.Now().Add(inviteLifetime),
+				}
+				if err := tx.Create(&invite).Error; err != nil {
+					return err
+				}
+			}
+
+			for _, role := range account.Roles {
+				event := models.SecurityEvent{
+					AccountID: account.ID,
+					ActorID:   options.ActorID,
+					Type:      models.SecurityEventRoleAssigned,
+					Details:   "role " + role.Name + " assigned by import",
+				}
+				if err := tx.Create(&event).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for i, account := range accounts {
+		result.Rows[i].AccountID = account.ID
+		if options.Credentials != CredentialsInvite {
+			continue
+		}
+		if account.Email == "" {
+			result.Rows[i].InviteToken = inviteTokens[i]
+			continue
+		}
+		result.Rows[i].InviteSent = true
+		result.invites = append(result.invites, pendingInvite{account: account, token: inviteTokens[i]})
+	}
+	result.Created = len(accounts)
+	result.inviteLifetime = inviteLifetime
+	return result, nil
+}
+
+// SendInvites emails the invite tokens of the imported accounts that have an
+// email address.
+func (r ImportResult) SendInvites() {
+	for _, invite := range r.invites {
+		if err := notifier.Send(inviteMessage(invite.account, invite.token, r.inviteLifetime)); err != nil {
+			log.Printf("Failed to send invite to account %d: %v", invite.account.ID, err)
+		}
+	}
+}
+
+// validateImportRows checks each row on its own and against the other rows
+// and existing accounts. It returns the accounts to create when all rows are
+// valid.
+func validateImportRows(rows []ImportRow, result *ImportResult) ([]models.Account, error) {
+	var roles []*models.Role
+	if err := config.DB.Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	rolesByName := make(map[string]*models.Role, len(roles))
+	for _, role := range roles {
+		rolesByName[role.Name] = role
+	}
+
+	var specializations []*models.Specialization
+	if err := config.DB.Find(&specializations).Error; err != nil {
+		return nil, err
+	}
+	specializationsByName := make(map[string]*models.Specialization, len(specializations))
+	for _, specialization := range specializations {
+		specializationsByName[strings.ToLower(specialization.Name)] = specialization
+	}
+
+	var usernames, emails, phones []string
+	for _, row := range rows {
+		usernames = append(usernames, strings.TrimSpace(row.Username))
+		emails = append(emails, strings.ToLower(strings.TrimSpace(row.Email)))
+		if phone, err := utils.NormalizePhone(row.Phone); err == nil {
+			phones = append(phones, phone)
+		}
+	}
+	takenUsernames, err := existingValues("username", "username IN ?", usernames)
+	if err != nil {
+		return nil, err
+	}
+	takenEmails, err := existingValues("LOWER(email)", "email <> '' AND LOWER(email) IN ?", emails)
+	if err != nil {
+		return nil, err
+	}
+	takenPhones, err := existingValues("phone", "phone <> '' AND phone IN ?", phones)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Valid = true
+	result.Rows = make([]ImportRowResult, len(rows))
+	accounts := make([]models.Account, len(rows))
+	seenUsernames := make(map[string]int)
+	seenEmails := make(map[string]int)
+	seenPhones := make(map[string]int)
+
+	for i, row := range rows {
+		line := i + 1
+		account := models.Account{
+			LastName:           strings.TrimSpace(row.LastName),
+			FirstName:          strings.TrimSpace(row.FirstName),
+			Username:           strings.TrimSpace(row.Username),
+			MustChangePassword: true,
+		}
+		var problems []string
+
+		if account.LastName == "" {
+			problems = append(problems, "lastName is required")
+		}
+		if account.FirstName == "" {
+			problems = append(problems, "firstName is required")
+		}
+		if account.Username == "" {
+			problems = append(problems, "username is required")
+		} else if takenUsernames[account.Username] {
+			problems = append(problems, "username already exists")
+		} else if previous, ok := seenUsernames[account.Username]; ok {
+			problems = append(problems, fmt.Sprintf("username duplicates row %d", previous))
+		} else {
+			seenUsernames[account.Username] = line
+		}
+
+		if email := strings.TrimSpace(row.Email); email != "" {
+			if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+				problems = append(problems, "email is invalid")
+			} else if key := strings.ToLower(email); takenEmails[key] {
+				problems = append(problems, "email is already registered")
+			} else if previous, ok := seenEmails[key]; ok {
+				problems = append(problems, fmt.Sprintf("email duplicates row %d", previous))
+			} else {
+				seenEmails[key] = line
+				account.Email = email
+			}
+		}
+
+		if strings.TrimSpace(row.Phone) != "" {
+			if phone, err := utils.NormalizePhone(row.Phone); err != nil {
+				problems = append(problems, err.Error())
+			} else if takenPhones[phone] {
+				problems = append(problems, "phone is already registered")
+			} else if previous, ok := seenPhones[phone]; ok {
+				problems = append(problems, fmt.Sprintf("phone duplicates row %d", previous))
+			} else {
+				seenPhones[phone] = line
+				account.Phone = phone
+			}
+		}
+
+		isDoctor := false
+		assigned := make(map[string]bool)
+		for _, name := range row.Roles {
+			name = models.NormalizeRoleName(name)
+			if name == "" || assigned[name] {
+				continue
+			}
+			role, ok := rolesByName[name]
+			if !ok {
+				problems = append(problems, "unknown role: "+name)
+				continue
+			}
+			assigned[name] = true
+			isDoctor = isDoctor || name == "doctor"
+			account.Roles = append(account.Roles, role)
+		}
+		if len(row.Roles) == 0 {
+			problems = append(problems, "at least one role is required")
+		}
+
+		for _, name := range row.Specializations {
+			name = strings.Join(strings.Fields(name), " ")
+			if name == "" {
+				continue
+			}
+			specialization, ok := specializationsByName[strings.ToLower(name)]
+			if !ok {
+				problems = append(problems, "unknown specialization: "+name)
+				continue
+			}
+			account.Specializations = append(account.Specializations, specialization)
+		}
+		if len(account.Specializations) > 0 && !isDoctor {
+			problems = append(problems, "specializations require the doctor role")
+		}
+
+		result.Rows[i] = ImportRowResult{Row: line, Username: account.Username, Errors: problems}
+		if len(problems) > 0 {
+			result.Valid = false
+		}
+		accounts[i] = account
+	}
+
+	return accounts, nil
+}
+
+// existingValues returns which of the values are already used by accounts,
+// including deleted ones, since the unique indexes cover them too.
+func existingValues(column, condition string, values []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(values) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	if err := config.DB.Unscoped().Model(&models.Account{}).Where(condition, values).Pluck(column, &found).Error; err != nil {
+		return nil, err
+	}
+	for _, value := range found {
+		existing[value] = true
+	}
+	return existing, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func inviteMessage(account models.Account, token string, lifetime time.Duration) notifier.Message {
+	instructions := "Use this invite token to set your password: " + token
+	if inviteURL := os.Getenv("INVITE_URL"); inviteURL != "" {
+		separator := "?"
+		if strings.Contains(inviteURL, "?") {
+			separator = "&"
+		}
+		instructions = "Open this link to set your password: " + inviteURL + separator + "token=" + url.QueryEscape(token)
+	}
+
+	return notifier.Message{
+		Channel: notifier.ChannelEmail,
+		To:      account.Email,
+		Subject: "Your account has been created",
+		Body: fmt.Sprintf("Hello, %s!\n\nAn account has been created for you.\n%s\n\nThe invite expires in %d days and can be used once.",
+			account.Username, instructions, int(lifetime.Hours()/24)),
+	}
+}
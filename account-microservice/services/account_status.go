@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+)
+
+var (
+	ErrAccountSuspended = errors.New("Account is suspended")
+	ErrAccountPending   = errors.New("Account is pending activation")
+)
+
+// CheckAccountStatus returns an error when the account may not sign in or
+// use its tokens.
+func CheckAccountStatus(account models.Account) error {
+	switch account.EffectiveStatus(time.Now()) {
+	case models.AccountSuspended:
+		return ErrAccountSuspended
+	case models.AccountPending:
+		return ErrAccountPending
+	}
+	return nil
+}
+
+// CheckAccountStatusByID loads the account and checks its status. Deleted
+// accounts are reported as suspended.
+func CheckAccountStatusByID(accountID uint) error {
+	var account models.Account
+	if err := config.DB.First(&account, accountID).Error; err != nil {
+		return ErrAccountSuspended
+	}
+	return CheckAccountStatus(account)
+}
+
+// ApplyAccountStatus marks the account's status as applied and, when it
+// blocks access, revokes every session of the account.
+func ApplyAccountStatus(account models.Account) error {
+	if account.Status != models.AccountActive {
+		if err := RevokeAllSessions(account.ID, fmt.Sprintf("account %s", account.Status)); err != nil {
+			return err
+		}
+	}
+	return config.DB.Model(&account).Update("applied_status", account.Status).Error
+}
+
+// ApplyDueStatusChanges applies scheduled status changes whose effective
+// date has passed.
+func ApplyDueStatusChanges() {
+	var accounts []models.Account
+	err := config.DB.
+		Where("status <> applied_status AND (status_effective_at IS NULL OR status_effective_at <= ?)", time.Now()).
+		Find(&accounts).Error
+	if err != nil {
+		log.Printf("Failed to load scheduled account status changes: %v", err)
+		return
+	}
+
+	for _, account := range accounts {
+		if err := ApplyAccountStatus(account); err != nil {
+			log.Printf("Failed to apply status %s to account %d: %v", account.Status, account.ID, err)
+			continue
+		}
+		log.Printf("Account %d is now %s", account.ID, account.Status)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"reflect"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"gorm.io/gorm"
+)
+
+const auditArchiveBatchSize = 500
+
+// AuditDiff keeps only the fields whose values differ between the two
+// snapshots.
+func AuditDiff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	changedBefore := make(map[string]interface{})
+	changedAfter := make(map[string]interface{})
+	for key, value := range before {
+		if other, ok := after[key]; !ok || !reflect.DeepEqual(value, other) {
+			changedBefore[key] = value
+		}
+	}
+	for key, value := range after {
+		if other, ok := before[key]; !ok || !reflect.DeepEqual(value, other) {
+			changedAfter[key] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// AccountAuditFields is the snapshot of an account compared by audit events.
+// The roles must be preloaded.
+func AccountAuditFields(account models.Account) map[string]interface{} {
+	roles := make([]string, 0, len(account.Roles))
+	for _, role := range account.Roles {
+		roles = append(roles, role.Name)
+	}
+
+	return map[string]interface{}{
+		"username":           account.Username,
+		"lastName":           account.LastName,
+		"firstName":          account.FirstName,
+		"email":              account.Email,
+		"phone":              account.Phone,
+		"roles":              roles,
+		"mustChangePassword": account.MustChangePassword,
+	}
+}
+
+// ArchiveAuditEvents moves audit events created before the cutoff to the
+// archive table and returns how many were moved.
+func ArchiveAuditEvents(cutoff time.Time) (int64, error) {
+	var archived int64
+	for {
+		var events []models.AuditEvent
+		if err := config.DB.Where("created_at < ?", cutoff).Order("id").Limit(auditArchiveBatchSize).Find(&events).Error; err != nil {
+			return archived, err
+		}
+		if len(events) == 0 {
+			return archived, nil
+		}
+
+		now := time.Now()
+		ids := make([]uint, 0, len(events))
+		rows := make([]models.ArchivedAuditEvent, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			rows = append(rows, models.ArchivedAuditEvent{
+				ID:         event.ID,
+				Action:     event.Action,
+				ActorID:    event.ActorID,
+				TargetID:   event.TargetID,
+				IP:         event.IP,
+				UserAgent:  event.UserAgent,
+				Before:     event.Before,
+				After:      event.After,
+				CreatedAt:  event.CreatedAt,
+				ArchivedAt: now,
+			})
+		}
+
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+			// Archiving is the only way audit events leave the table.
+			return tx.Session(&gorm.Session{SkipHooks: true}).Where("id IN ?", ids).Delete(&models.AuditEvent{}).Error
+		})
+		if err != nil {
+			return archived, err
+		}
+		archived += int64(len(events))
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"account-microservice/models"
+)
+
+func TestAuditDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     map[string]interface{}
+		after      map[string]interface{}
+		wantBefore map[string]interface{}
+		wantAfter  map[string]interface{}
+	}{
+		{
+			name:       "unchanged",
+			before:     map[string]interface{}{"username": "ivanov", "roles": []string{"user"}},
+			after:      map[string]interface{}{"username": "ivanov", "roles": []string{"user"}},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{},
+		},
+		{
+			name:       "changed field",
+			before:     map[string]interface{}{"username": "ivanov", "email": "old@example.com"},
+			after:      map[string]interface{}{"username": "ivanov", "email": "new@example.com"},
+			wantBefore: map[string]interface{}{"email": "old@example.com"},
+			wantAfter:  map[string]interface{}{"email": "new@example.com"},
+		},
+		{
+			name:       "changed slice",
+			before:     map[string]interface{}{"roles": []string{"user"}},
+			after:      map[string]interface{}{"roles": []string{"user", "doctor"}},
+			wantBefore: map[string]interface{}{"roles": []string{"user"}},
+			wantAfter:  map[string]interface{}{"roles": []string{"user", "doctor"}},
+		},
+		{
+			name:       "added field",
+			before:     map[string]interface{}{},
+			after:      map[string]interface{}{"phone": "+79990000000"},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{"phone": "+79990000000"},
+		},
+		{
+			name:       "removed field",
+			before:     map[string]interface{}{"phone": "+79990000000"},
+			after:      map[string]interface{}{},
+			wantBefore: map[string]interface{}{"phone": "+79990000000"},
+			wantAfter:  map[string]interface{}{},
+		},
+		{
+			name:       "nil snapshots",
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBefore, gotAfter := AuditDiff(tt.before, tt.after)
+			if !reflect.DeepEqual(gotBefore, tt.wantBefore) {
+				t.Errorf("before = %v, want %v", gotBefore, tt.wantBefore)
+			}
+			if !reflect.DeepEqual(gotAfter, tt.wantAfter) {
+				t.Errorf("after = %v, want %v", gotAfter, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestAccountAuditFieldsDiff(t *testing.T) {
+	account := models.Account{
+		Username:  "ivanov",
+		LastName:  "Ivanov",
+		FirstName: "Ivan",
+		Roles:     []*models.Role{{Name: "user"}},
+	}
+	before := AccountAuditFields(account)
+
+	account.LastName = "Petrov"
+	account.Roles = append(account.Roles, &models.Role{Name: "doctor"})
+	gotBefore, gotAfter := AuditDiff(before, AccountAuditFields(account))
+
+	wantBefore := map[string]interface{}{"lastName": "Ivanov", "roles": []string{"user"}}
+	wantAfter := map[string]interface{}{"lastName": "Petrov", "roles": []string{"user", "doctor"}}
+	if !reflect.DeepEqual(gotBefore, wantBefore) {
+		t.Errorf("before = %v, want %v", gotBefore, wantBefore)
+	}
+	if !reflect.DeepEqual(gotAfter, wantAfter) {
+		t.Errorf("after = %v, want %v", gotAfter, wantAfter)
+	}
+}
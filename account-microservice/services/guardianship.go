@@ -0,0 +1,22 @@
+package services
+
+import (
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+)
+
+// ActiveDependents returns the IDs of the accounts the guardian may currently
+// act for.
+func ActiveDependents(guardianID uint) ([]uint, error) {
+	dependents := []uint{}
+	err := config.DB.Model(&models.Guardianship{}).
+		Joins("JOIN accounts ON accounts.id = guardianships.dependent_id AND accounts.deleted_at IS NULL").
+		Where("guardianships.guardian_id = ? AND guardianships.status = ?", guardianID, models.GuardianshipActive).
+		Where("guardianships.expires_at IS NULL OR guardianships.expires_at > ?", time.Now()).
+		Order("guardianships.dependent_id").
+		Distinct().
+		Pluck("guardianships.dependent_id", &dependents).Error
+	return dependents, err
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	legacyDoctorTable        = "doctors"
+	legacyDoctorArchiveTable = "legacy_doctors_archive"
+)
+
+var ErrLegacyDoctorsArchived = errors.New("legacy doctors were already archived to " + legacyDoctorArchiveTable)
+
+type legacyDoctor struct {
+	ID             uint
+	LastName       string
+	FirstName      string
+	Specialization string
+}
+
+// LegacyDoctorMigration reports what MigrateLegacyDoctors did.
+type LegacyDoctorMigration struct {
+	Rows      int    `json:"rows"`
+	Migrated  int    `json:"migrated"`
+	Unmatched []uint `json:"unmatched"`
+	Dropped   bool   `json:"dropped"`
+}
+
+// HasLegacyDoctorTable reports whether the pre-profile "doctors" table is
+// still present.
+func HasLegacyDoctorTable() bool {
+	return config.DB.Migrator().HasTable(legacyDoctorTable)
+}
+
+// MigrateLegacyDoctors copies the pre-profile "doctors" table to an archive
+// table and adds each row's specialization to the doctor account with the
+// same name. The table is dropped only once the archive holds every row;
+// rows without a matching account stay in the archive.
+func MigrateLegacyDoctors() (LegacyDoctorMigration, error) {
+	var result LegacyDoctorMigration
+	if !HasLegacyDoctorTable() {
+		return result, nil
+	}
+	if config.DB.Migrator().HasTable(legacyDoctorArchiveTable) {
+		return result, ErrLegacyDoctorsArchived
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var rows []legacyDoctor
+		if err := tx.Table(legacyDoctorTable).Order("id").Find(&rows).Error; err != nil {
+			return err
+		}
+		result.Rows = len(rows)
+
+		if err := tx.Exec("CREATE TABLE " + legacyDoctorArchiveTable + " AS TABLE " + legacyDoctorTable).Error; err != nil {
+			return err
+		}
+		var archived int64
+		if err := tx.Table(legacyDoctorArchiveTable).Count(&archived).Error; err != nil {
+			return err
+		}
+		if archived != int64(len(rows)) {
+			return fmt.Errorf("archived %d of %d legacy doctors", archived, len(rows))
+		}
+
+		for _, row := range rows {
+			migrated, err := migrateLegacyDoctor(tx, row)
+			if err != nil {
+				return fmt.Errorf("legacy doctor %d: %w", row.ID, err)
+			}
+			if migrated {
+				result.Migrated++
+			} else {
+				result.Unmatched = append(result.Unmatched, row.ID)
+			}
+		}
+
+		if err := tx.Migrator().DropTable(legacyDoctorTable); err != nil {
+			return err
+		}
+		result.Dropped = true
+		return nil
+	})
+	if err != nil {
+		return LegacyDoctorMigration{}, err
+	}
+	return result, nil
+}
+
+// migrateLegacyDoctor reports false when no single doctor account has the
+// row's name.
+func migrateLegacyDoctor(tx *gorm.DB, row legacyDoctor) (bool, error) {
+	var accounts []models.Account
+	err := tx.Joins("JOIN account_roles ON accounts.id = account_roles.account_id").
+		Joins("JOIN roles ON roles.id = account_roles.role_id").
+		Where("roles.name = ?", "doctor").
+		Where("LOWER(accounts.last_name) = LOWER(?) AND LOWER(accounts.first_name) = LOWER(?)", row.LastName, row.FirstName).
+		Find(&accounts).Error
+	if err != nil {
+		return false, err
+	}
+	if len(accounts) != 1 {
+		return false, nil
+	}
+
+	name := strings.Join(strings.Fields(row.Specialization), " ")
+	if name == "" {
+		return true, nil
+	}
+
+	var specialization models.Specialization
+	if err := tx.Where("LOWER(name) = LOWER(?)", name).Attrs(models.Specialization{Name: name}).FirstOrCreate(&specialization).Error; err != nil {
+		return false, err
+	}
+	return true, tx.Model(&accounts[0]).Association("Specializations").Append(&specialization)
+}
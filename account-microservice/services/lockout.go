@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LockoutPolicy struct {
+	MaxUserFailures int
+	MaxIPFailures   int
+	Window          time.Duration
+	LockoutDuration time.Duration
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	// MaxChallengeAttempts is the number of invalid codes accepted for one
+	// MFA challenge before the password has to be entered again.
+	MaxChallengeAttempts int
+}
+
+func CurrentLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxUserFailures: config.GetInt("LOGIN_MAX_FAILURES", 5),
+		MaxIPFailures:   config.GetInt("LOGIN_MAX_FAILURES_PER_IP", 50),
+		Window:          config.GetDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LockoutDuration: config.GetDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		BaseDelay:       config.GetDuration("LOGIN_DELAY_BASE", time.Second),
+		MaxDelay:        config.GetDuration("LOGIN_DELAY_MAX", 30*time.Second),
+
+		MaxChallengeAttempts: config.GetInt("MFA_MAX_ATTEMPTS", 3),
+	}
+}
+
+func UserLockoutKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func IPLockoutKey(ip string) string {
+	return "ip:" + ip
+}
+
+func ChallengeAttemptKey(jti string) string {
+	return "challenge:" + jti
+}
+
+// LoginRetryAfter reports how long the caller has to wait before another
+// sign-in attempt is accepted for any of the given keys.
+func LoginRetryAfter(keys ...string) (time.Duration, error) {
+	policy := CurrentLockoutPolicy()
+	now := time.Now()
+
+	var attempts []models.LoginAttempt
+	if err := config.DB.Where("key IN ?", keys).Find(&attempts).Error; err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, attempt := range attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			wait = maxDuration(wait, attempt.LockedUntil.Sub(now))
+		}
+		if attempt.LastFailureAt.Before(now.Add(-policy.Window)) {
+			continue
+		}
+		nextAttempt := attempt.LastFailureAt.Add(policy.delay(attempt.Failures))
+		wait = maxDuration(wait, nextAttempt.Sub(now))
+	}
+	return wait, nil
+}
+
+// RecordLoginFailure increments the failure counters and returns the keys
+// that became locked by this attempt.
+func RecordLoginFailure(userKey, ipKey string) ([]string, error) {
+	policy := CurrentLockoutPolicy()
+	limits := map[string]int{userKey: policy.MaxUserFailures, ipKey: policy.MaxIPFailures}
+
+	var locked []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		windowStart := now.Add(-policy.Window)
+
+		for _, key := range []string{userKey, ipKey} {
+			attempt := models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", windowStart),
+					"last_failure_at": now,
+				}),
+			}).Create(&attempt).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("key = ?", key).First(&attempt).Error; err != nil {
+				return err
+			}
+			if attempt.Failures < limits[key] {
+				continue
+			}
+			if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+				continue
+			}
+
+			if err := tx.Model(&attempt).Update("locked_until", now.Add(policy.LockoutDuration)).Error; err != nil {
+				return err
+			}
+			locked = append(locked, key)
+		}
+		return nil
+	})
+	return locked, err
+}
+
+// RecordChallengeFailure counts an invalid code entered for the MFA challenge
+// and reports whether the challenge has used up its attempts.
+func RecordChallengeFailure(jti string) (bool, error) {
+	now := time.Now()
+	attempt := models.LoginAttempt{Key: ChallengeAttemptKey(jti), Failures: 1, LastFailureAt: now}
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("login_attempts.failures + 1"),
+			"last_failure_at": now,
+		}),
+	}).Create(&attempt).Error
+	if err != nil {
+		return false, err
+	}
+	return ChallengeExhausted(jti)
+}
+
+// ChallengeExhausted reports whether the MFA challenge may no longer be used.
+func ChallengeExhausted(jti string) (bool, error) {
+	attempt, err := GetLoginAttempt(ChallengeAttemptKey(jti))
+	if err != nil || attempt == nil {
+		return false, err
+	}
+	return attempt.Failures >= CurrentLockoutPolicy().MaxChallengeAttempts, nil
+}
+
+func ClearLoginFailures(key string) error {
+	return config.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+func GetLoginAttempt(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := config.DB.Where("key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func ActiveLockouts() ([]models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	err := config.DB.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&attempts).Error
+	return attempts, err
+}
+
+func SweepLoginAttempts() (int64, error) {
+	now := time.Now()
+	result := config.DB.
+		Where("last_failure_at < ?", now.Add(-CurrentLockoutPolicy().Window)).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Delete(&models.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
+
+func (p LockoutPolicy) delay(failures int) time.Duration {
+	if failures <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
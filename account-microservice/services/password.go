@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"gorm.io/gorm"
+)
+
+var ErrPasswordReused = errors.New("Password was used recently, choose a different one")
+
+func CurrentPasswordPolicy() utils.PasswordPolicy {
+	return utils.PasswordPolicy{
+		MinLength:      config.GetInt("PASSWORD_MIN_LENGTH", 10),
+		RequireUpper:   config.GetBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:   config.GetBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:   config.GetBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:  config.GetBool("PASSWORD_REQUIRE_SYMBOL", false),
+		RejectBreached: config.GetBool("PASSWORD_REJECT_BREACHED", true),
+	}
+}
+
+func passwordHistorySize() int {
+	return config.GetInt("PASSWORD_HISTORY_SIZE", 5)
+}
+
+// CheckPasswordReuse rejects the password when it matches the current one or
+// any of the last PASSWORD_HISTORY_SIZE passwords of the account.
+func CheckPasswordReuse(account models.Account, password string) error {
+	if account.ID == 0 {
+		return nil
+	}
+
+	if account.Password != "" {
+		if ok, _ := utils.CheckPassword(account.Password, password); ok {
+			return ErrPasswordReused
+		}
+	}
+
+	size := passwordHistorySize()
+	if size <= 0 {
+		return nil
+	}
+
+	var history []models.PasswordHistory
+	if err := config.DB.Where("account_id = ?", account.ID).Order("created_at DESC").Limit(size).Find(&history).Error; err != nil {
+		return err
+	}
+	for _, entry := range history {
+		if ok, _ := utils.CheckPassword(entry.PasswordHash, password); ok {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// RecordPasswordHistory stores the new hash and drops entries older than the
+// configured history size.
+func RecordPasswordHistory(db *gorm.DB, accountID uint, passwordHash string) error {
+	size := passwordHistorySize()
+	if size <= 0 {
+		return nil
+	}
+
+	if err := db.Create(&models.PasswordHistory{AccountID: accountID, PasswordHash: passwordHash}).Error; err != nil {
+		return err
+	}
+
+	keep := db.Model(&models.PasswordHistory{}).
+		Select("id").
+		Where("account_id = ?", accountID).
+		Order("created_at DESC, id DESC").
+		Limit(size)
+	return db.Where("account_id = ? AND id NOT IN (?)", accountID, keep).Delete(&models.PasswordHistory{}).Error
+}
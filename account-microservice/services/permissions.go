@@ -0,0 +1,68 @@
+package services
+
+import (
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+)
+
+// AccountPermissions returns the union of the permissions granted globally
+// by the account's roles. Hospital-scoped roles contribute only their
+// permissions that are not tied to a hospital. The roles must be preloaded.
+func AccountPermissions(account models.Account) ([]string, error) {
+	permissions := []string{}
+	if len(account.Roles) == 0 {
+		return permissions, nil
+	}
+
+	globalRoleIDs := []uint{}
+	scopedRoleIDs := []uint{}
+	for _, role := range account.Roles {
+		if role.HospitalScoped {
+			scopedRoleIDs = append(scopedRoleIDs, role.ID)
+		} else {
+			globalRoleIDs = append(globalRoleIDs, role.ID)
+		}
+	}
+
+	err := config.DB.Model(&models.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id IN ? OR (role_permissions.role_id IN ? AND permissions.name NOT IN ?)",
+			globalRoleIDs, scopedRoleIDs, models.HospitalPermissions).
+		Order("permissions.name").
+		Pluck("permissions.name", &permissions).Error
+	return permissions, err
+}
+
+// HospitalAccess returns the account's hospital affiliations together with
+// the hospital permissions their roles grant there.
+func HospitalAccess(accountID uint) ([]utils.HospitalAccess, error) {
+	var affiliations []models.HospitalAffiliation
+	err := config.DB.Preload("Role.Permissions").
+		Where("account_id = ?", accountID).
+		Order("hospital_id").
+		Find(&affiliations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	access := make([]utils.HospitalAccess, 0, len(affiliations))
+	for _, affiliation := range affiliations {
+		if affiliation.Role == nil {
+			continue
+		}
+		permissions := []string{}
+		for _, permission := range affiliation.Role.Permissions {
+			if models.IsHospitalPermission(permission.Name) {
+				permissions = append(permissions, permission.Name)
+			}
+		}
+		access = append(access, utils.HospitalAccess{
+			HospitalID:  affiliation.HospitalID,
+			Role:        affiliation.Role.Name,
+			Permissions: permissions,
+		})
+	}
+	return access, nil
+}
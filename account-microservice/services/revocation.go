@@ -0,0 +1,84 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+	"account-microservice/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func RevokeToken(jti string, expiresAt time.Time, reason string) error {
+	return config.DB.Create(&models.Revocation{
+		Kind:      models.RevocationKindToken,
+		Value:     jti,
+		Reason:    reason,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+func RevokeAccount(accountID uint, reason string) error {
+	return revokeIssuedBefore(models.RevocationKindAccount, accountID, reason)
+}
+
+func RevokeSession(sessionID uint, reason string) error {
+	return revokeIssuedBefore(models.RevocationKindSession, sessionID, reason)
+}
+
+func RevokeClient(clientID string, reason string) error {
+	return revokeValueIssuedBefore(models.RevocationKindClient, clientID, reason)
+}
+
+func revokeIssuedBefore(kind string, id uint, reason string) error {
+	return revokeValueIssuedBefore(kind, strconv.FormatUint(uint64(id), 10), reason)
+}
+
+func revokeValueIssuedBefore(kind string, value string, reason string) error {
+	now := time.Now()
+	return config.DB.Create(&models.Revocation{
+		Kind:      kind,
+		Value:     value,
+		Reason:    reason,
+		RevokedAt: now,
+		ExpiresAt: now.Add(utils.AccessTokenLifetime),
+	}).Error
+}
+
+func IsRevoked(claims jwt.MapClaims) (bool, error) {
+	jti, _ := claims["jti"].(string)
+	clientID, _ := claims["client_id"].(string)
+	iat, _ := claims["iat"].(float64)
+	issuedAt := time.UnixMilli(int64(iat * 1000))
+
+	query := config.DB.Model(&models.Revocation{}).
+		Where("expires_at > ?", time.Now()).
+		Where(config.DB.
+			Where("kind = ? AND value = ?", models.RevocationKindToken, jti).
+			Or("kind = ? AND value = ? AND revoked_at > ?", models.RevocationKindAccount, claimID(claims["account_id"]), issuedAt).
+			Or("kind = ? AND value = ? AND revoked_at > ?", models.RevocationKindSession, claimID(claims["sid"]), issuedAt).
+			Or("kind = ? AND value = ? AND revoked_at > ?", models.RevocationKindClient, clientID, issuedAt))
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func ActiveRevocations() ([]models.Revocation, error) {
+	var revocations []models.Revocation
+	err := config.DB.Where("expires_at > ?", time.Now()).Find(&revocations).Error
+	return revocations, err
+}
+
+func claimID(value interface{}) string {
+	id, ok := value.(float64)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"time"
+
+	"account-microservice/config"
+	"account-microservice/models"
+
+	"gorm.io/gorm"
+)
+
+// RevokeAllSessions ends every session and refresh token of the account and
+// revokes its outstanding access tokens.
+func RevokeAllSessions(accountID uint, reason string) error {
+	now := time.Now()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("account_id = ? AND revoked_at IS NULL", accountID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Token{}).
+			Where("account_id = ? AND revoked_at IS NULL", accountID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return RevokeAccount(accountID, reason)
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+
+// NormalizePhone strips formatting characters and checks that the result
+// looks like an international phone number.
+func NormalizePhone(phone string) (string, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(phone))
+	if !phonePattern.MatchString(normalized) {
+		return "", fmt.Errorf("Invalid phone number")
+	}
+	if !strings.HasPrefix(normalized, "+") {
+		normalized = "+" + normalized
+	}
+	return normalized, nil
+}
+
+func RandomDigits(length int) (string, error) {
+	var builder strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(byte('0' + digit.Int64()))
+	}
+	return builder.String(), nil
+}
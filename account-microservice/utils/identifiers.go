@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var digitsPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// NormalizeSNILS strips formatting from an insurance number (СНИЛС) and
+// checks its length and control number.
+func NormalizeSNILS(snils string) (string, error) {
+	normalized := stripIdentifier(snils)
+	if len(normalized) != 11 || !digitsPattern.MatchString(normalized) {
+		return "", fmt.Errorf("SNILS must contain 11 digits")
+	}
+
+	// Numbers up to 001-001-998 were issued before the control number was
+	// introduced and cannot be checked.
+	if normalized[:9] <= "001001998" {
+		return normalized, nil
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(normalized[i]-'0') * (9 - i)
+	}
+	control := sum % 101
+	if control == 100 {
+		control = 0
+	}
+
+	if fmt.Sprintf("%02d", control) != normalized[9:] {
+		return "", fmt.Errorf("Invalid SNILS control number")
+	}
+	return normalized, nil
+}
+
+// FormatSNILS renders a normalized SNILS as XXX-XXX-XXX YY.
+func FormatSNILS(snils string) string {
+	if len(snils) != 11 {
+		return snils
+	}
+	return snils[:3] + "-" + snils[3:6] + "-" + snils[6:9] + " " + snils[9:]
+}
+
+// NormalizeOMS strips formatting from a unified compulsory medical insurance
+// policy number (ОМС) and checks its control digit. The FFOMS algorithm is
+// equivalent to the Luhn checksum over all 16 digits.
+func NormalizeOMS(oms string) (string, error) {
+	normalized := stripIdentifier(oms)
+	if len(normalized) != 16 || !digitsPattern.MatchString(normalized) {
+		return "", fmt.Errorf("OMS policy number must contain 16 digits")
+	}
+
+	sum := 0
+	for i := 0; i < len(normalized); i++ {
+		digit := int(normalized[len(normalized)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+
+	if sum%10 != 0 {
+		return "", fmt.Errorf("Invalid OMS policy control digit")
+	}
+	return normalized, nil
+}
+
+func stripIdentifier(value string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(value))
+}
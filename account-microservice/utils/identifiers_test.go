@@ -0,0 +1,75 @@
+package utils
+
+import "testing"
+
+func TestNormalizeSNILS(t *testing.T) {
+	tests := []struct {
+		name  string
+		snils string
+		want  string
+		ok    bool
+	}{
+		{"formatted", "112-233-445 95", "11223344595", true},
+		{"digits only", "11223344595", "11223344595", true},
+		{"wrong control number", "112-233-445 96", "", false},
+		{"checksum 100 gives 00", "001-508-815 00", "00150881500", true},
+		{"checksum 100 rejects 100", "001-508-815 10", "", false},
+		{"checksum 101 gives 00", "001-437-544 00", "00143754400", true},
+		{"checksum 101 rejects 01", "001-437-544 01", "", false},
+		{"checksum below 100", "001-326-678 99", "00132667899", true},
+		{"checksum above 101", "001-318-759 01", "00131875901", true},
+		{"last unchecked number", "001-001-998 12", "00100199812", true},
+		{"first checked number", "001-001-999 65", "00100199965", true},
+		{"first checked number, wrong control", "001-001-999 00", "", false},
+		{"too short", "112-233-445 9", "", false},
+		{"too long", "112-233-445 950", "", false},
+		{"letters", "112-233-44A 95", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSNILS(tt.snils)
+			if (err == nil) != tt.ok {
+				t.Fatalf("NormalizeSNILS(%q) error = %v, want ok = %v", tt.snils, err, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeSNILS(%q) = %q, want %q", tt.snils, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSNILS(t *testing.T) {
+	if got := FormatSNILS("11223344595"); got != "112-233-445 95" {
+		t.Errorf("FormatSNILS = %q, want %q", got, "112-233-445 95")
+	}
+}
+
+func TestNormalizeOMS(t *testing.T) {
+	tests := []struct {
+		name string
+		oms  string
+		want string
+		ok   bool
+	}{
+		{"valid", "1234567890123452", "1234567890123452", true},
+		{"formatted", "1234 5678 9012 3452", "1234567890123452", true},
+		{"wrong control digit", "1234567890123456", "", false},
+		{"swapped digits", "2134567890123452", "", false},
+		{"all zeros", "0000000000000000", "0000000000000000", true},
+		{"too short", "123456789012345", "", false},
+		{"letters", "123456789012345A", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeOMS(tt.oms)
+			if (err == nil) != tt.ok {
+				t.Fatalf("NormalizeOMS(%q) error = %v, want ok = %v", tt.oms, err, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeOMS(%q) = %q, want %q", tt.oms, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// internalServiceScope is the scope of the tokens the account service issues
+// to itself for calls to the other services.
+const internalServiceScope = "timetables:read hospitals:read"
+
+var internalClient = &http.Client{Timeout: 10 * time.Second}
+
+type DoctorAvailability struct {
+	DoctorID     uint       `json:"doctorId"`
+	HospitalIDs  []uint     `json:"hospitalIds"`
+	NextFreeSlot *time.Time `json:"nextFreeSlot"`
+}
+
+type AvailabilityQuery struct {
+	DoctorIDs  []uint     `json:"doctorIds"`
+	HospitalID uint       `json:"hospitalId,omitempty"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+}
+
+type HospitalSummary struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// availabilityBatchSize is the largest number of doctors timetable_service
+// accepts in one availability request.
+const availabilityBatchSize = 1000
+
+// FetchDoctorAvailability asks timetable_service where the doctors work and
+// when their soonest free slot is, in one call per availabilityBatchSize
+// doctors.
+func FetchDoctorAvailability(query AvailabilityQuery) ([]DoctorAvailability, error) {
+	var result []DoctorAvailability
+	doctorIDs := query.DoctorIDs
+	for start := 0; start < len(doctorIDs); start += availabilityBatchSize {
+		query.DoctorIDs = doctorIDs[start:min(start+availabilityBatchSize, len(doctorIDs))]
+
+		payload, err := json.Marshal(query)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []DoctorAvailability
+		if err := callInternalService("TIMETABLE_SERVICE_URL", http.MethodPost, "/api/Timetable/Availability", payload, &batch); err != nil {
+			return nil, err
+		}
+		result = append(result, batch...)
+	}
+	return result, nil
+}
+
+// FetchHospitals loads the hospitals with the given IDs from hospital_service
+// in a single call.
+func FetchHospitals(ids []uint) ([]HospitalSummary, error) {
+	var result []HospitalSummary
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.FormatUint(uint64(id), 10))
+	}
+
+	err := callInternalService("HOSPITAL_SERVICE_URL", http.MethodGet, "/api/Hospitals/Batch?ids="+url.QueryEscape(strings.Join(values, ",")), nil, &result)
+	return result, err
+}
+
+func callInternalService(urlKey, method, path string, payload []byte, result interface{}) error {
+	baseURL := os.Getenv(urlKey)
+	if baseURL == "" {
+		return fmt.Errorf("%s is not configured", urlKey)
+	}
+
+	token, err := GenerateServiceToken("account-service", internalServiceScope)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(method, strings.TrimRight(baseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := internalClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s responded with %s", method, path, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
@@ -0,0 +1,481 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"account-microservice/models"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	KeyUseAccess  = "access"
+	KeyUseRefresh = "refresh"
+
+	keyringReloadInterval = time.Minute
+)
+
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+	ExpiresAt *time.Time
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Keyring holds every non-expired key of one use. The newest active key
+// signs new tokens; retired keys keep verifying until their expiry.
+type Keyring struct {
+	db        *gorm.DB
+	use       string
+	algorithm string
+	lifetime  time.Duration
+
+	mu       sync.RWMutex
+	active   *SigningKey
+	keys     map[string]*SigningKey
+	loadedAt time.Time
+}
+
+var AccessKeys *Keyring
+var RefreshKeys *Keyring
+
+func InitKeyrings(db *gorm.DB) {
+	accessAlg := os.Getenv("ACCESS_TOKEN_ALG")
+	if accessAlg == "" {
+		accessAlg = "RS256"
+	}
+
+	AccessKeys = &Keyring{db: db, use: KeyUseAccess, algorithm: accessAlg, lifetime: AccessTokenLifetime}
+	RefreshKeys = &Keyring{db: db, use: KeyUseRefresh, algorithm: "HS256", lifetime: RefreshTokenLifetime}
+
+	for _, keyring := range []*Keyring{AccessKeys, RefreshKeys} {
+		if err := keyring.bootstrap(); err != nil {
+			log.Fatalf("Failed to initialize %s keyring: %v", keyring.use, err)
+		}
+	}
+}
+
+func KeyringFor(use string) (*Keyring, error) {
+	switch use {
+	case KeyUseAccess:
+		return AccessKeys, nil
+	case KeyUseRefresh:
+		return RefreshKeys, nil
+	default:
+		return nil, fmt.Errorf("unknown key use %q", use)
+	}
+}
+
+func (k *Keyring) Use() string {
+	return k.use
+}
+
+func (k *Keyring) Algorithm() string {
+	return k.algorithm
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key, err := k.Active()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SignKey)
+}
+
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no key id")
+	}
+
+	key, err := k.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, fmt.Errorf("signing key %q has expired", kid)
+	}
+
+	return key.VerifyKey, nil
+}
+
+func (k *Keyring) Active() (*SigningKey, error) {
+	k.reloadIfStale()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == nil {
+		return nil, fmt.Errorf("no active %s signing key", k.use)
+	}
+	return k.active, nil
+}
+
+func (k *Keyring) JWKS() []JWK {
+	k.reloadIfStale()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := []JWK{}
+	for _, key := range k.keys {
+		if jwk, ok := key.JWK(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+	return keys
+}
+
+func (k *Keyring) Rotate() (*models.SigningKey, error) {
+	record, err := k.newRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(k.lifetime + keyringReloadInterval)
+
+	err = k.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SigningKey{}).
+			Where("use = ? AND active = ?", k.use, true).
+			Updates(map[string]interface{}{"active": false, "retired_at": now, "expires_at": expiresAt}).Error; err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (k *Keyring) bootstrap() error {
+	var count int64
+	if err := k.db.Model(&models.SigningKey{}).Where("use = ? AND active = ?", k.use, true).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		record, err := k.initialRecord()
+		if err != nil {
+			return err
+		}
+		if err := k.db.Create(record).Error; err != nil {
+			return err
+		}
+		log.Printf("Created %s signing key %s", k.use, record.Kid)
+	}
+
+	return k.load()
+}
+
+func (k *Keyring) reloadIfStale() {
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) > keyringReloadInterval
+	k.mu.RUnlock()
+
+	if stale {
+		if err := k.load(); err != nil {
+			log.Printf("Failed to reload %s keyring: %v", k.use, err)
+		}
+	}
+}
+
+func (k *Keyring) lookup(kid string) (*SigningKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	k.reloadIfStale()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok = k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (k *Keyring) load() error {
+	var records []models.SigningKey
+	if err := k.db.Where("use = ? AND (expires_at IS NULL OR expires_at > ?)", k.use, time.Now()).
+		Order("created_at DESC").
+		Find(&records).Error; err != nil {
+		return err
+	}
+
+	keys := make(map[string]*SigningKey)
+	var active *SigningKey
+	for _, record := range records {
+		key, err := parseRecord(record)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %v", record.Kid, err)
+		}
+		keys[key.ID] = key
+		if record.Active && active == nil {
+			active = key
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+func (k *Keyring) initialRecord() (*models.SigningKey, error) {
+	switch {
+	case k.use == KeyUseAccess && os.Getenv("ACCESS_TOKEN_PRIVATE_KEY_FILE") != "":
+		signer, err := readPrivateKey(os.Getenv("ACCESS_TOKEN_PRIVATE_KEY_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		return newAsymmetricRecord(k.use, k.algorithm, signer)
+	case k.use == KeyUseRefresh && os.Getenv("REFRESH_TOKEN_SECRET") != "":
+		return newSymmetricRecord(k.use, []byte(os.Getenv("REFRESH_TOKEN_SECRET")))
+	default:
+		return k.newRecord()
+	}
+}
+
+func (k *Keyring) newRecord() (*models.SigningKey, error) {
+	if k.algorithm == "HS256" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return newSymmetricRecord(k.use, secret)
+	}
+
+	signer, err := generatePrivateKey(k.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return newAsymmetricRecord(k.use, k.algorithm, signer)
+}
+
+func newSymmetricRecord(use string, secret []byte) (*models.SigningKey, error) {
+	kid, err := RandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		Kid:       kid,
+		Use:       use,
+		Algorithm: "HS256",
+		Material:  base64.StdEncoding.EncodeToString(secret),
+		Active:    true,
+	}, nil
+}
+
+func newAsymmetricRecord(use string, alg string, signer crypto.Signer) (*models.SigningKey, error) {
+	if _, err := signingMethod(alg, signer); err != nil {
+		return nil, err
+	}
+
+	kid, err := thumbprint(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		Kid:       kid,
+		Use:       use,
+		Algorithm: alg,
+		Material:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		Active:    true,
+	}, nil
+}
+
+func parseRecord(record models.SigningKey) (*SigningKey, error) {
+	key := &SigningKey{ID: record.Kid, ExpiresAt: record.ExpiresAt}
+
+	if record.Algorithm == "HS256" {
+		secret, err := base64.StdEncoding.DecodeString(record.Material)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.SignKey = secret
+		key.VerifyKey = secret
+		return key, nil
+	}
+
+	signer, err := parsePrivateKey([]byte(record.Material))
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := signingMethod(record.Algorithm, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Method = method
+	key.SignKey = signer
+	key.VerifyKey = signer.Public()
+	return key, nil
+}
+
+func signingMethod(alg string, signer crypto.Signer) (jwt.SigningMethod, error) {
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		if alg != "RS256" {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", alg)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PrivateKey:
+		if alg != "EdDSA" {
+			return nil, fmt.Errorf("Ed25519 key cannot be used with %s", alg)
+		}
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", signer)
+	}
+}
+
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{Use: "sig", Alg: k.Method.Alg(), Kid: k.ID}
+	switch pub := k.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKey(data)
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+func generatePrivateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %s", alg)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint used as the key id.
+func thumbprint(pub crypto.PublicKey) (string, error) {
+	var members interface{}
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		}
+	case ed25519.PublicKey:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{
+			Crv: "Ed25519",
+			Kty: "OKP",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
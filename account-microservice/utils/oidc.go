@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const AuthorizationCodeLifetime = time.Minute * 5
+
+var SupportedScopes = []string{"openid", "profile", "email", "phone", "offline_access"}
+
+// ServiceScopes can only be granted to internal services through the
+// client_credentials grant.
+var ServiceScopes = []string{"accounts:read", "hospitals:read", "timetables:read", "revocations:read"}
+
+func Issuer() string {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		issuer = "http://localhost"
+	}
+	return strings.TrimRight(issuer, "/")
+}
+
+type IDTokenClaims struct {
+	AccountID uint
+	ClientID  string
+	Nonce     string
+	AuthTime  time.Time
+	SessionID uint
+	Profile   map[string]interface{}
+}
+
+func GenerateIDToken(input IDTokenClaims) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       Issuer(),
+		"sub":       strconv.FormatUint(uint64(input.AccountID), 10),
+		"aud":       input.ClientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(AccessTokenLifetime).Unix(),
+		"auth_time": input.AuthTime.Unix(),
+	}
+	if input.Nonce != "" {
+		claims["nonce"] = input.Nonce
+	}
+	if input.SessionID != 0 {
+		claims["sid"] = strconv.FormatUint(uint64(input.SessionID), 10)
+	}
+	for key, value := range input.Profile {
+		claims[key] = value
+	}
+
+	return AccessKeys.Sign(claims)
+}
+
+// VerifyPKCE checks an RFC 7636 code verifier against the S256 challenge.
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+const (
+	ChallengeSSO         = "sso"
+	ChallengeOIDCConsent = "oidc_consent"
+	SSOLifetime          = time.Hour * 8
+)
+
+// GenerateSSOToken signs the browser session cookie used by the
+// authorization endpoint.
+func GenerateSSOToken(accountID uint, authTime time.Time) (string, error) {
+	jti, err := RandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	return RefreshKeys.Sign(jwt.MapClaims{
+		"account_id": accountID,
+		"purpose":    ChallengeSSO,
+		"auth_time":  authTime.Unix(),
+		"jti":        jti,
+		"exp":        time.Now().Add(SSOLifetime).Unix(),
+	})
+}
+
+// GenerateConsentTicket binds a consent form to the account and the
+// authorization request it was rendered for.
+func GenerateConsentTicket(accountID uint, request string) (string, error) {
+	jti, err := RandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	return RefreshKeys.Sign(jwt.MapClaims{
+		"account_id": accountID,
+		"purpose":    ChallengeOIDCConsent,
+		"request":    request,
+		"jti":        jti,
+		"exp":        time.Now().Add(ChallengeLifetime).Unix(),
+	})
+}
+
+func ContainsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 Appendix B.
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		ok        bool
+	}{
+		{"appendix B pair", verifier, challenge, true},
+		{"wrong verifier", verifier[:len(verifier)-1] + "l", challenge, false},
+		{"plain challenge", verifier, verifier, false},
+		{"padded challenge", verifier, challenge + "=", false},
+		{"empty challenge", verifier, "", false},
+		{"verifier too short", verifier[:42], challenge, false},
+		{"verifier too long", strings.Repeat("a", 129), challenge, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := VerifyPKCE(tt.verifier, tt.challenge); ok != tt.ok {
+				t.Errorf("VerifyPKCE(%q, %q) = %v, want %v", tt.verifier, tt.challenge, ok, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed breached_passwords.txt
+var breachedPasswordList string
+
+var (
+	breachedPasswordsOnce sync.Once
+	breachedPasswords     map[string]struct{}
+)
+
+type PasswordPolicy struct {
+	MinLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	RejectBreached bool
+}
+
+// Validate returns an error describing every rule the password breaks.
+func (p PasswordPolicy) Validate(username, password string) error {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+	if p.RejectBreached && IsBreachedPassword(password) {
+		problems = append(problems, "is too common and appears in breached password lists")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Password %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func IsBreachedPassword(password string) bool {
+	breachedPasswordsOnce.Do(func() {
+		breachedPasswords = make(map[string]struct{})
+		for _, line := range strings.Split(breachedPasswordList, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				breachedPasswords[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	})
+
+	_, found := breachedPasswords[strings.ToLower(password)]
+	return found
+}
+
+// GeneratePassword returns a random password that satisfies the policy and
+// does not contain the username.
+func (p PasswordPolicy) GeneratePassword(username string) (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+	const symbols = "-_.!@#%+="
+
+	length := max(p.MinLength, 16)
+	for {
+		password := make([]byte, 0, length+1)
+		for i := 0; i < length; i++ {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+			if err != nil {
+				return "", err
+			}
+			password = append(password, alphabet[n.Int64()])
+		}
+		if p.RequireSymbol {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(symbols))))
+			if err != nil {
+				return "", err
+			}
+			password = append(password, symbols[n.Int64()])
+		}
+
+		if p.Validate(username, string(password)) == nil {
+			return string(password), nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	defaultPolicy := PasswordPolicy{
+		MinLength:      10,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RejectBreached: true,
+	}
+	symbolPolicy := defaultPolicy
+	symbolPolicy.RequireSymbol = true
+	lenientPolicy := PasswordPolicy{MinLength: 4}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		username string
+		password string
+		problems []string
+	}{
+		{"valid", defaultPolicy, "ivanov", "Correct7Horse", nil},
+		{"too short", defaultPolicy, "ivanov", "Short7a", []string{"at least 10 characters"}},
+		{"length counts runes", defaultPolicy, "ivanov", "Пароль7Тест", nil},
+		{"short in runes, long in bytes", defaultPolicy, "ivanov", "Пароль7Те", []string{"at least 10 characters"}},
+		{"no uppercase", defaultPolicy, "ivanov", "correct7horse", []string{"uppercase letter"}},
+		{"no lowercase", defaultPolicy, "ivanov", "CORRECT7HORSE", []string{"lowercase letter"}},
+		{"no digit", defaultPolicy, "ivanov", "CorrectHorse", []string{"digit"}},
+		{"symbol required", symbolPolicy, "ivanov", "Correct7Horse", []string{"symbol"}},
+		{"symbol present", symbolPolicy, "ivanov", "Correct7Horse!", nil},
+		{"contains username", defaultPolicy, "Ivanov", "myIVANOV2024x", []string{"username"}},
+		{"breached", defaultPolicy, "ivanov", "Password123", []string{"breached"}},
+		{"breached check disabled", lenientPolicy, "ivanov", "password", nil},
+		{"every problem", defaultPolicy, "ivanov", "ivanov", []string{"at least 10 characters", "uppercase letter", "digit", "username"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.username, tt.password)
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("Validate(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate(%q) = nil, want an error mentioning %q", tt.password, tt.problems)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("Validate(%q) = %q, want it to mention %q", tt.password, err, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestIsBreachedPasswordIgnoresCase(t *testing.T) {
+	for _, password := range []string{"qwerty123", "QWERTY123", "QwErTy123"} {
+		if !IsBreachedPassword(password) {
+			t.Errorf("IsBreachedPassword(%q) = false, want true", password)
+		}
+	}
+	if IsBreachedPassword("# Commonly breached passwords, compared case-insensitively.") {
+		t.Error("comment line treated as a breached password")
+	}
+}
+
+func TestPasswordPolicyGeneratePassword(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 20, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true, RejectBreached: true}
+
+	password, err := policy.GeneratePassword("ivanov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(password) < policy.MinLength {
+		t.Errorf("generated password %q is shorter than %d", password, policy.MinLength)
+	}
+	if err := policy.Validate("ivanov", password); err != nil {
+		t.Errorf("generated password %q breaks the policy: %v", password, err)
+	}
+}
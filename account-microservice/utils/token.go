@@ -1,35 +1,172 @@
 package utils
 
 import (
-	"os"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func GenerateAccessToken(accountID uint, roles []string) (string, error) {
+const (
+    AccessTokenLifetime  = time.Hour * 1
+    RefreshTokenLifetime = time.Hour * 24 * 7
+)
+
+// HospitalAccess is an entry of the hospitals claim: the role an account
+// holds in a hospital and the permissions it grants there.
+type HospitalAccess struct {
+    HospitalID  uint     `json:"hospitalId"`
+    Role        string   `json:"role"`
+    Permissions []string `json:"permissions"`
+}
+
+// Actor is the act claim of an impersonation token: the administrator who
+// acts as the token's account.
+type Actor struct {
+    AccountID uint   `json:"account_id"`
+    Username  string `json:"username"`
+}
+
+type AccessClaims struct {
+    AccountID   uint
+    Roles       []string
+    Permissions []string
+    Dependents  []uint
+    Hospitals   []HospitalAccess
+    SessionID   uint
+    Verified    bool
+    ClientID    string
+    Scope       string
+    Actor       *Actor
+    ReadOnly    bool
+    Lifetime    time.Duration
+}
+
+func GenerateAccessToken(input AccessClaims) (string, error) {
+    jti, err := RandomString(16)
+    if err != nil {
+        return "", err
+    }
+
+    lifetime := AccessTokenLifetime
+    if input.Lifetime > 0 {
+        lifetime = input.Lifetime
+    }
+
+    now := time.Now()
     claims := jwt.MapClaims{
-        "account_id": accountID,
-        "roles":      roles,
-        "exp":        time.Now().Add(time.Hour * 1).Unix(),
+        "account_id":  input.AccountID,
+        "roles":       input.Roles,
+        "permissions": input.Permissions,
+        "verified":    input.Verified,
+        "jti":         jti,
+        "iat":         float64(now.UnixMilli()) / 1000,
+        "exp":         now.Add(lifetime).Unix(),
+    }
+    if len(input.Dependents) > 0 {
+        claims["dependents"] = input.Dependents
+    }
+    if len(input.Hospitals) > 0 {
+        claims["hospitals"] = input.Hospitals
+    }
+    if input.Actor != nil {
+        claims["act"] = input.Actor
+        claims["read_only"] = input.ReadOnly
+    }
+    if input.SessionID != 0 {
+        claims["sid"] = input.SessionID
+    }
+    if input.ClientID != "" {
+        claims["client_id"] = input.ClientID
+        claims["scope"] = input.Scope
     }
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(os.Getenv("ACCESS_TOKEN_SECRET")))
+    return AccessKeys.Sign(claims)
+}
+
+// GenerateServiceToken issues an access token for an internal service. It has
+// no account_id claim, so user-only endpoints reject it.
+func GenerateServiceToken(clientID string, scope string) (string, error) {
+    jti, err := RandomString(16)
+    if err != nil {
+        return "", err
+    }
+
+    now := time.Now()
+    return AccessKeys.Sign(jwt.MapClaims{
+        "sub":       clientID,
+        "client_id": clientID,
+        "scope":     scope,
+        "jti":       jti,
+        "iat":       float64(now.UnixMilli()) / 1000,
+        "exp":       now.Add(AccessTokenLifetime).Unix(),
+    })
 }
 
 func GenerateRefreshToken(accountID uint) (string, error) {
+    jti, err := RandomString(16)
+    if err != nil {
+        return "", err
+    }
+
     claims := jwt.MapClaims{
         "account_id": accountID,
-        "exp":        time.Now().Add(time.Hour * 24 * 7).Unix(),
+        "jti":        jti,
+        "exp":        time.Now().Add(RefreshTokenLifetime).Unix(),
     }
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(os.Getenv("REFRESH_TOKEN_SECRET")))
+    return RefreshKeys.Sign(claims)
 }
 
-func ValidateToken(tokenString string, secret string) (*jwt.Token, error) {
-    return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-        return []byte(secret), nil
-    })
+func ValidateAccessToken(tokenString string) (*jwt.Token, error) {
+    return jwt.Parse(tokenString, AccessKeys.Keyfunc)
+}
+
+func ValidateRefreshToken(tokenString string) (*jwt.Token, error) {
+    return jwt.Parse(tokenString, RefreshKeys.Keyfunc)
+}
+
+const (
+    ChallengeLifetime = time.Minute * 5
+
+    ChallengeMFA           = "mfa"
+    ChallengeMFAEnrollment = "mfa_enroll"
+    ChallengePasswordChange = "password_change"
+)
+
+func GenerateChallengeToken(accountID uint, purpose string, deviceName string) (string, error) {
+    jti, err := RandomString(16)
+    if err != nil {
+        return "", err
+    }
+
+    claims := jwt.MapClaims{
+        "account_id": accountID,
+        "purpose":    purpose,
+        "device":     deviceName,
+        "jti":        jti,
+        "exp":        time.Now().Add(ChallengeLifetime).Unix(),
+    }
+
+    return RefreshKeys.Sign(claims)
+}
+
+func ValidateChallengeToken(tokenString string, purposes ...string) (jwt.MapClaims, error) {
+    token, err := jwt.Parse(tokenString, RefreshKeys.Keyfunc)
+    if err != nil || !token.Valid {
+        return nil, fmt.Errorf("invalid or expired challenge")
+    }
+
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok {
+        return nil, fmt.Errorf("invalid challenge claims")
+    }
+
+    purpose, _ := claims["purpose"].(string)
+    for _, allowed := range purposes {
+        if purpose == allowed {
+            return claims, nil
+        }
+    }
+    return nil, fmt.Errorf("challenge cannot be used here")
 }
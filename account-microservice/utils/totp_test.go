@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed "12345678901234567890" from RFC 6238
+// Appendix B, base32-encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPRFC6238Vectors(t *testing.T) {
+	// Appendix B lists 8-digit codes; 6-digit codes are their last six digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("ValidateTOTP(%q) at %d rejected a valid code", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("ValidateTOTP(%q) at %d matched step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// "081804" belongs to step 37037036 (1111111080..1111111109).
+	const code = "081804"
+	const step = int64(37037036)
+
+	tests := []struct {
+		name string
+		unix int64
+		ok   bool
+	}{
+		{"two steps early", 1111111020, false},
+		{"one step early", 1111111050, true},
+		{"same step", 1111111080, true},
+		{"one step late", 1111111110, true},
+		{"one step late, last second", 1111111139, true},
+		{"two steps late", 1111111140, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ok := ValidateTOTP(rfc6238Secret, code, time.Unix(tt.unix, 0))
+			if ok != tt.ok {
+				t.Fatalf("ValidateTOTP at %d = %v, want %v", tt.unix, ok, tt.ok)
+			}
+			if ok && matched != step {
+				t.Errorf("ValidateTOTP at %d matched step %d, want %d", tt.unix, matched, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	// Callers store the last accepted step and only accept later ones, so a
+	// code reused within the skew window must map to the step it was
+	// first accepted for.
+	first, ok := ValidateTOTP(rfc6238Secret, "081804", time.Unix(1111111109, 0))
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+
+	replayed, ok := ValidateTOTP(rfc6238Secret, "081804", time.Unix(1111111125, 0))
+	if !ok {
+		t.Fatal("code within the skew window rejected")
+	}
+	if replayed > first {
+		t.Errorf("replayed code matched step %d, later than the accepted step %d", replayed, first)
+	}
+}
+
+func TestValidateTOTPRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfc6238Secret, "287083"},
+		{"short code", rfc6238Secret, "28708"},
+		{"long code", rfc6238Secret, "2870820"},
+		{"invalid secret", "not base32!", "287082"},
+		{"empty code", rfc6238Secret, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+				t.Errorf("ValidateTOTP(%q, %q) accepted invalid input", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPAcceptsLowercaseSecret(t *testing.T) {
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", time.Unix(59, 0)); !ok {
+		t.Error("lowercase secret rejected")
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"document_service/config"
 	"document_service/models"
 	"document_service/utils"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 type HistoryController struct {
@@ -30,26 +32,32 @@ func (h *HistoryController) GetHistoryByAccountID(c *gin.Context) {
 		return
 	}
 
-	roles, err := h.AccountService.GetRolesByID(accessToken)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles"})
-		return
-	}
-
+	claims := c.MustGet("claims").(jwt.MapClaims)
 	isOwner := uint(accountID) == currentUserID
-	isDoctor := containsRole(roles, "doctor")
+	canReadAll, readableHospitals := utils.PermittedHospitals(claims, "history.read")
+	isGuardian := utils.IsGuardianOf(claims, uint(accountID))
 
-	if !isOwner && !isDoctor {
+	if !isOwner && !canReadAll && !isGuardian && len(readableHospitals) == 0 {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
+	// Hospital staff only see the records made in their hospitals.
+	query := config.DB.Where("pacient_id = ?", accountID)
+	if !isOwner && !canReadAll && !isGuardian {
+		query = query.Where("hospital_id IN ?", readableHospitals)
+	}
+
 	var histories []models.History
-	if err := config.DB.Where("pacient_id = ?", accountID).Find(&histories).Error; err != nil {
+	if err := query.Find(&histories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve histories"})
 		return
 	}
 
+	if !isOwner && !canReadAll && isGuardian && !recordGuardianAccess(c, uint(accountID), currentUserID, 0) {
+		return
+	}
+
 	c.JSON(http.StatusOK, histories)
 }
 
@@ -74,20 +82,20 @@ func (h *HistoryController) GetHistoryByID(c *gin.Context) {
 		return
 	}
 
-	roles, err := h.AccountService.GetRolesByID(accessToken)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles"})
-		return
-	}
-
+	claims := c.MustGet("claims").(jwt.MapClaims)
 	isOwner := history.PacientID == currentUserID
-	isDoctor := containsRole(roles, "doctor")
+	canRead := utils.HasHospitalPermission(claims, history.HospitalID, "history.read")
+	isGuardian := utils.IsGuardianOf(claims, history.PacientID)
 
-	if !isOwner && !isDoctor {
+	if !isOwner && !canRead && !isGuardian {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
+	if !isOwner && !canRead && !recordGuardianAccess(c, history.PacientID, currentUserID, history.ID) {
+		return
+	}
+
 	c.JSON(http.StatusOK, history)
 }
 
@@ -112,15 +120,24 @@ func (h *HistoryController) CreateHistory(c *gin.Context) {
 		return
 	}
 
+	if !requireHospitalPermission(c, input.HospitalID, "history.write") {
+		return
+	}
+
 	accessToken := c.GetString("accessToken")
 	if accessToken == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token not found"})
 		return
 	}
 
-	_, err = h.AccountService.GetRolesByAccountID(input.PacientID, accessToken)
+	pacientPermissions, err := h.AccountService.GetPermissionsByAccountID(input.PacientID, accessToken)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get pacient roles"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get pacient permissions"})
+		return
+	}
+
+	if !containsPermission(pacientPermissions, "history.own") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pacient must have permission 'history.own'"})
 		return
 	}
 
@@ -155,6 +172,10 @@ func (h *HistoryController) UpdateHistory(c *gin.Context) {
 		return
 	}
 
+	if !requireHospitalPermission(c, history.HospitalID, "history.write") {
+		return
+	}
+
 	var input struct {
 		Date       string `json:"date"`
 		PacientID  uint   `json:"pacientId"`
@@ -179,19 +200,22 @@ func (h *HistoryController) UpdateHistory(c *gin.Context) {
 	}
 	if input.PacientID != 0 {
 		accessToken := c.GetString("accessToken")
-		pacientRoles, err := h.AccountService.GetRolesByAccountID(input.PacientID, accessToken)
+		pacientPermissions, err := h.AccountService.GetPermissionsByAccountID(input.PacientID, accessToken)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get pacient roles"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get pacient permissions"})
 			return
 		}
 
-		if !containsRole(pacientRoles, "user") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Pacient must have role 'user'"})
+		if !containsPermission(pacientPermissions, "history.own") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pacient must have permission 'history.own'"})
 			return
 		}
 		history.PacientID = input.PacientID
 	}
 	if input.HospitalID != 0 {
+		if !requireHospitalPermission(c, input.HospitalID, "history.write") {
+			return
+		}
 		history.HospitalID = input.HospitalID
 	}
 	if input.DoctorID != 0 {
@@ -212,11 +236,32 @@ func (h *HistoryController) UpdateHistory(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
-func containsRole(roles []string, role string) bool {
-	for _, r := range roles {
-		if r == role {
+// requireHospitalPermission responds with 403 unless the access token grants
+// the permission in the hospital.
+func requireHospitalPermission(c *gin.Context, hospitalID uint, permission string) bool {
+	if !utils.HasHospitalPermission(c.MustGet("claims").(jwt.MapClaims), hospitalID, permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Permission %s required for hospital %d", permission, hospitalID)})
+		return false
+	}
+	return true
+}
+
+func containsPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
 			return true
 		}
 	}
 	return false
 }
+
+// recordGuardianAccess logs a guardian reading a dependent's records. The
+// records are not returned if the access cannot be logged.
+func recordGuardianAccess(c *gin.Context, pacientID, guardianID, historyID uint) bool {
+	access := models.HistoryAccess{PacientID: pacientID, ActorID: guardianID, HistoryID: historyID}
+	if err := config.DB.Create(&access).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record access"})
+		return false
+	}
+	return true
+}
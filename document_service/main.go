@@ -11,7 +11,7 @@ import (
 
 func main() {
 	config.InitDB()
-	config.DB.AutoMigrate(&models.History{})
+	config.DB.AutoMigrate(&models.History{}, &models.HistoryAccess{})
 	accountService := utils.NewAccountService()
 	r := gin.Default()
 	routes.InitHistoryRoutes(r, accountService)
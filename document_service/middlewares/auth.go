@@ -1,12 +1,14 @@
 package middlewares
 
 import (
+	"log"
 	"net/http"
 	"strings"
 
 	"document_service/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 func AuthMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
@@ -25,53 +27,62 @@ func AuthMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		isValid, err := accountService.ValidateToken(tokenString)
+		claims, err := accountService.ParseToken(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Error validating token", "details": err.Error()})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token", "details": err.Error()})
 			return
 		}
-		if !isValid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+
+		if _, ok := claims["account_id"].(float64); !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User token required"})
+			return
+		}
+
+		if !checkImpersonation(c, claims) {
 			return
 		}
 
 		c.Set("accessToken", tokenString)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
 
-func RoleMiddleware(accountService *utils.AccountService, allowedRoles []string) gin.HandlerFunc {
+// PermissionMiddleware requires the access token to carry the permission,
+// globally or in at least one hospital. Handlers check the hospital itself.
+func PermissionMiddleware(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		accessToken, exists := c.Get("accessToken")
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token not found"})
-			return
-		}
+		claims, _ := c.Get("claims")
+		mapClaims, _ := claims.(jwt.MapClaims)
 
-		roles, err := accountService.GetRolesByID(accessToken.(string))
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles", "details": err.Error()})
+		if all, hospitalIDs := utils.PermittedHospitals(mapClaims, permission); !all && len(hospitalIDs) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission " + permission + " required"})
 			return
 		}
 
-		hasRole := false
-		for _, role := range roles {
-			for _, allowedRole := range allowedRoles {
-				if role == allowedRole {
-					hasRole = true
-					break
-				}
-			}
-			if hasRole {
-				break
-			}
-		}
+		c.Next()
+	}
+}
 
-		if !hasRole {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-			return
-		}
+// checkImpersonation logs requests made with an impersonation token, whose
+// act claim names the administrator, and rejects writes when it is read-only.
+func checkImpersonation(c *gin.Context, claims jwt.MapClaims) bool {
+	actor, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return true
+	}
 
-		c.Next()
+	log.Printf("Impersonated request %s %s: account %v acting as account %v",
+		c.Request.Method, c.Request.URL.Path, actor["account_id"], claims["account_id"])
+
+	readOnly, _ := claims["read_only"].(bool)
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if readOnly {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Impersonation token is read-only"})
+			return false
+		}
 	}
+	return true
 }
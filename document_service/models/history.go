@@ -16,3 +16,13 @@ type History struct {
     UpdatedAt  time.Time `json:"-"`
     DeletedAt  *time.Time `gorm:"index" json:"-"`
 }
+
+// HistoryAccess records a guardian reading a dependent's medical history.
+// HistoryID is zero when the whole history was listed.
+type HistoryAccess struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    PacientID uint      `gorm:"index" json:"pacientId"`
+    ActorID   uint      `gorm:"index" json:"actorId"`
+    HistoryID uint      `json:"historyId,omitempty"`
+    CreatedAt time.Time `json:"createdAt"`
+}
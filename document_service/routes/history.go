@@ -17,7 +17,7 @@ func InitHistoryRoutes(r *gin.Engine, accountService *utils.AccountService) {
     {
         historyRoutes.GET("/Account/:id",middlewares.AuthMiddleware(accountService),historyController.GetHistoryByAccountID,)
         historyRoutes.GET("/:id", middlewares.AuthMiddleware(accountService),historyController.GetHistoryByID,)
-        historyRoutes.POST("", middlewares.AuthMiddleware(accountService),middlewares.RoleMiddleware(accountService, []string{"admin", "manager", "doctor"}),historyController.CreateHistory,)
-        historyRoutes.PUT("/:id", middlewares.AuthMiddleware(accountService),middlewares.RoleMiddleware(accountService, []string{"admin", "manager", "doctor"}), historyController.UpdateHistory, )
+        historyRoutes.POST("", middlewares.AuthMiddleware(accountService),middlewares.PermissionMiddleware("history.write"),historyController.CreateHistory,)
+        historyRoutes.PUT("/:id", middlewares.AuthMiddleware(accountService),middlewares.PermissionMiddleware("history.write"), historyController.UpdateHistory, )
     }
 }
@@ -1,3 +1,5 @@
+//go:generate sh ../../shared/sync.sh
+
 package utils
 
 import (
@@ -6,11 +8,15 @@ import (
 	"os"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 type AccountService struct {
-	client  *resty.Client
-	baseURL string
+	client        *resty.Client
+	baseURL       string
+	keys          *KeySet
+	revocations   *RevocationList
+	serviceTokens *ServiceTokenSource
 }
 
 func NewAccountService() *AccountService {
@@ -22,65 +28,50 @@ func NewAccountService() *AccountService {
 	client.SetHostURL(baseURL)
 
 	return &AccountService{
-		client:  client,
-		baseURL: baseURL,
+		client:        client,
+		baseURL:       baseURL,
+		keys:          NewKeySet(client),
+		revocations:   NewRevocationList(client),
+		serviceTokens: NewServiceTokenSource("accounts:read"),
 	}
 }
 
-func (a *AccountService) ValidateToken(token string) (bool, error) {
-	var result struct {
-		IsValid bool `json:"isValid"`
-	}
-
-	resp, err := a.client.R().
-		SetQueryParam("accessToken", token).
-		SetResult(&result).
-		Get("/api/Authentication/Validate")
-
+func (a *AccountService) ParseToken(token string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(token, a.keys.Keyfunc)
 	if err != nil {
-		return false, err
-	}
-
-	if resp.StatusCode() != 200 {
-		return false, fmt.Errorf("failed to validate token: %s", resp.Status())
+		return nil, err
 	}
 
-	return result.IsValid, nil
-}
-
-func (a *AccountService) GetRolesByID(token string) ([]string, error) {
-	var result struct {
-		Roles []struct {
-			Name string `json:"name"`
-		} `json:"roles"`
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return nil, fmt.Errorf("invalid token claims")
 	}
 
-	resp, err := a.client.R().
-		SetHeader("Authorization", "Bearer "+token).
-		SetResult(&result).
-		Get("/api/Accounts/Me")
-
+	revoked, err := a.revocations.IsRevoked(claims)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("failed to get user roles, status: %s", resp.Status())
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
 	}
 
-	var roles []string
-	for _, role := range result.Roles {
-		roles = append(roles, role.Name)
-	}
-
-	return roles, nil
+	return claims, nil
 }
 
-func (a *AccountService) GetRolesByAccountID(accountID uint, token string) ([]string, error) {
+// GetPermissionsByAccountID looks up another account's permissions. When a
+// service client is configured it calls the account service with its own
+// token instead of forwarding the end user's.
+func (a *AccountService) GetPermissionsByAccountID(accountID uint, token string) ([]string, error) {
+	if a.serviceTokens != nil {
+		serviceToken, err := a.serviceTokens.Token()
+		if err != nil {
+			return nil, err
+		}
+		token = serviceToken
+	}
+
 	var result struct {
-		Roles []struct {
-			Name string `json:"name"`
-		} `json:"roles"`
+		Permissions []string `json:"permissions"`
 	}
 
 	resp, err := a.client.R().
@@ -89,19 +80,14 @@ func (a *AccountService) GetRolesByAccountID(accountID uint, token string) ([]st
 		Get(fmt.Sprintf("/api/Accounts/%d/roles", accountID))
 
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при запросе ролей аккаунта %d: %v", accountID, err)
+		return nil, fmt.Errorf("ошибка при запросе прав аккаунта %d: %v", accountID, err)
 	}
 
 	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("не удалось получить роли для аккаунта ID %d: статус: %s, ответ: %s", accountID, resp.Status(), resp.String())
-	}
-
-	var roles []string
-	for _, role := range result.Roles {
-		roles = append(roles, role.Name)
+		return nil, fmt.Errorf("не удалось получить права для аккаунта ID %d: статус: %s, ответ: %s", accountID, resp.Status(), resp.String())
 	}
 
-	return roles, nil
+	return result.Permissions, nil
 }
 
 func (a *AccountService) GetAccountID(token string) (uint, error) {
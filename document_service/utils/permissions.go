@@ -0,0 +1,66 @@
+package utils
+
+import "github.com/golang-jwt/jwt/v4"
+
+// HasPermission reports whether the access token claims grant the permission.
+func HasPermission(claims jwt.MapClaims, permission string) bool {
+	permissions, _ := claims["permissions"].([]interface{})
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGuardianOf reports whether the access token lets its holder act on behalf
+// of the account.
+func IsGuardianOf(claims jwt.MapClaims, accountID uint) bool {
+	dependents, _ := claims["dependents"].([]interface{})
+	for _, dependent := range dependents {
+		if id, ok := dependent.(float64); ok && uint(id) == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasHospitalPermission reports whether the access token claims grant the
+// permission globally or through an affiliation with the hospital.
+func HasHospitalPermission(claims jwt.MapClaims, hospitalID uint, permission string) bool {
+	if HasPermission(claims, permission) {
+		return true
+	}
+	_, hospitalIDs := PermittedHospitals(claims, permission)
+	for _, id := range hospitalIDs {
+		if id == hospitalID {
+			return true
+		}
+	}
+	return false
+}
+
+// PermittedHospitals returns the hospitals in which the access token claims
+// grant the permission. all is true when the permission is granted globally.
+func PermittedHospitals(claims jwt.MapClaims, permission string) (all bool, hospitalIDs []uint) {
+	if HasPermission(claims, permission) {
+		return true, nil
+	}
+
+	hospitals, _ := claims["hospitals"].([]interface{})
+	for _, entry := range hospitals {
+		hospital, _ := entry.(map[string]interface{})
+		id, ok := hospital["hospitalId"].(float64)
+		if !ok {
+			continue
+		}
+		permissions, _ := hospital["permissions"].([]interface{})
+		for _, granted := range permissions {
+			if granted == permission {
+				hospitalIDs = append(hospitalIDs, uint(id))
+				break
+			}
+		}
+	}
+	return false, hospitalIDs
+}
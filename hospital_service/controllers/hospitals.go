@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"hospital_service/config"
 	"hospital_service/models"
@@ -48,6 +49,42 @@ func GetHospitals(c *gin.Context) {
 	c.JSON(http.StatusOK, hospitals)
 }
 
+// GetHospitalsBatch returns the hospitals with the given comma-separated IDs.
+// Unknown IDs are skipped.
+func GetHospitalsBatch(c *gin.Context) {
+	var ids []uint
+	for _, value := range strings.Split(c.Query("ids"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid 'ids' parameter"})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+
+	if len(ids) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At most 1000 IDs are allowed"})
+		return
+	}
+
+	hospitals := []models.Hospital{}
+	if len(ids) == 0 {
+		c.JSON(http.StatusOK, hospitals)
+		return
+	}
+
+	if err := config.DB.Preload("Rooms").Where("id IN ?", ids).Order("id").Find(&hospitals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hospitals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hospitals)
+}
+
 func GetHospitalByID(c *gin.Context) {
 	id := c.Param("id")
 
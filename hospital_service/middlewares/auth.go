@@ -1,72 +1,159 @@
 package middlewares
 
 import (
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"hospital_service/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 func AuthMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		claims, ok := authenticate(c, accountService)
+		if !ok {
 			return
 		}
 
-		parts := strings.Fields(authHeader)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be 'Bearer {token}'"})
+		if _, ok := claims["account_id"].(float64); !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User token required"})
 			return
 		}
 
-		tokenString := parts[1]
+		if !checkImpersonation(c, claims) {
+			return
+		}
 
-		isValid, err := accountService.ValidateToken(tokenString)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Error validating token", "details": err.Error()})
+		c.Next()
+	}
+}
+
+// UserOrServiceMiddleware accepts user tokens as well as service tokens
+// issued by the account service that carry the given scope.
+func UserOrServiceMiddleware(accountService *utils.AccountService, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := authenticate(c, accountService)
+		if !ok {
+			return
+		}
+
+		if _, ok := claims["account_id"].(float64); ok {
+			if !checkImpersonation(c, claims) {
+				return
+			}
+			c.Next()
 			return
 		}
-		if !isValid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+
+		clientID, _ := claims["client_id"].(string)
+		tokenScope, _ := claims["scope"].(string)
+		if clientID == "" || !hasScope(tokenScope, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Scope " + scope + " required"})
 			return
 		}
 
-		c.Set("accessToken", tokenString)
+		c.Set("service", true)
+		c.Set("client_id", clientID)
 		c.Next()
 	}
 }
 
-func AdminMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
+func authenticate(c *gin.Context, accountService *utils.AccountService) (jwt.MapClaims, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		return nil, false
+	}
+
+	parts := strings.Fields(authHeader)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be 'Bearer {token}'"})
+		return nil, false
+	}
+
+	tokenString := parts[1]
+
+	claims, err := accountService.ParseToken(tokenString)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token", "details": err.Error()})
+		return nil, false
+	}
+
+	c.Set("accessToken", tokenString)
+	c.Set("claims", claims)
+	return claims, true
+}
+
+func hasScope(tokenScope string, scope string) bool {
+	for _, granted := range strings.Fields(tokenScope) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionMiddleware requires the access token to carry the permission.
+func PermissionMiddleware(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		accessToken, exists := c.Get("accessToken")
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token not found"})
+		claims, _ := c.Get("claims")
+		mapClaims, _ := claims.(jwt.MapClaims)
+
+		if !utils.HasPermission(mapClaims, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission " + permission + " required"})
 			return
 		}
 
-		roles, err := accountService.GetUserRoles(accessToken.(string))
+		c.Next()
+	}
+}
+
+// HospitalPermissionMiddleware requires the access token to carry the
+// permission globally or for the hospital in the :id path parameter.
+func HospitalPermissionMiddleware(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		mapClaims, _ := claims.(jwt.MapClaims)
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles", "details": err.Error()})
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid hospital ID"})
 			return
 		}
 
-		isAdmin := false
-		for _, role := range roles {
-			if role == "admin" {
-				isAdmin = true
-				break
-			}
-		}
-
-		if !isAdmin {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+		if !utils.HasHospitalPermission(mapClaims, uint(id), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Permission %s required for hospital %d", permission, id)})
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// checkImpersonation logs requests made with an impersonation token, whose
+// act claim names the administrator, and rejects writes when it is read-only.
+func checkImpersonation(c *gin.Context, claims jwt.MapClaims) bool {
+	actor, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return true
+	}
+
+	log.Printf("Impersonated request %s %s: account %v acting as account %v",
+		c.Request.Method, c.Request.URL.Path, actor["account_id"], claims["account_id"])
+
+	readOnly, _ := claims["read_only"].(bool)
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if readOnly {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Impersonation token is read-only"})
+			return false
+		}
+	}
+	return true
+}
@@ -11,12 +11,13 @@ import (
 func InitHospitalRoutes(r *gin.Engine, accountService *utils.AccountService) {
     hospitalRoutes := r.Group("/api/Hospitals")
     {
-        hospitalRoutes.GET("/", middlewares.AuthMiddleware(accountService), controllers.GetHospitals)
-        hospitalRoutes.GET("/:id", middlewares.AuthMiddleware(accountService), controllers.GetHospitalByID)
-        hospitalRoutes.GET("/:id/Rooms", middlewares.AuthMiddleware(accountService), controllers.GetHospitalRooms)
+        hospitalRoutes.GET("/", middlewares.UserOrServiceMiddleware(accountService, "hospitals:read"), controllers.GetHospitals)
+        hospitalRoutes.GET("/Batch", middlewares.UserOrServiceMiddleware(accountService, "hospitals:read"), controllers.GetHospitalsBatch)
+        hospitalRoutes.GET("/:id", middlewares.UserOrServiceMiddleware(accountService, "hospitals:read"), controllers.GetHospitalByID)
+        hospitalRoutes.GET("/:id/Rooms", middlewares.UserOrServiceMiddleware(accountService, "hospitals:read"), controllers.GetHospitalRooms)
 
-        hospitalRoutes.POST("/", middlewares.AuthMiddleware(accountService), middlewares.AdminMiddleware(accountService), controllers.CreateHospital)
-        hospitalRoutes.PUT("/:id", middlewares.AuthMiddleware(accountService), middlewares.AdminMiddleware(accountService), controllers.UpdateHospital)
-        hospitalRoutes.DELETE("/:id", middlewares.AuthMiddleware(accountService), middlewares.AdminMiddleware(accountService), controllers.DeleteHospital)
+        hospitalRoutes.POST("/", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("hospital.write"), controllers.CreateHospital)
+        hospitalRoutes.PUT("/:id", middlewares.AuthMiddleware(accountService), middlewares.HospitalPermissionMiddleware("hospital.write"), controllers.UpdateHospital)
+        hospitalRoutes.DELETE("/:id", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("hospital.write"), controllers.DeleteHospital)
     }
 }
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RevocationList caches the account service's revocation list. The list is
+// fetched with this service's own token, which needs the revocations:read
+// scope.
+type RevocationList struct {
+	client        *resty.Client
+	serviceTokens *ServiceTokenSource
+	ttl           time.Duration
+	mu            sync.RWMutex
+	tokens        map[string]bool
+	accounts      map[string]time.Time
+	sessions      map[string]time.Time
+	clients       map[string]time.Time
+	fetchedAt     time.Time
+}
+
+type revocation struct {
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+func NewRevocationList(client *resty.Client) *RevocationList {
+	ttl := 10 * time.Second
+	if value := os.Getenv("REVOCATION_CACHE_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			ttl = parsed
+		}
+	}
+
+	serviceTokens := NewServiceTokenSource("revocations:read")
+	if serviceTokens == nil {
+		log.Fatal("SERVICE_CLIENT_ID is not set, it is required to fetch token revocations")
+	}
+
+	return &RevocationList{client: client, serviceTokens: serviceTokens, ttl: ttl}
+}
+
+func (r *RevocationList) IsRevoked(claims jwt.MapClaims) (bool, error) {
+	if err := r.refreshIfStale(); err != nil {
+		return false, err
+	}
+
+	jti, _ := claims["jti"].(string)
+	iat, _ := claims["iat"].(float64)
+	issuedAt := time.UnixMilli(int64(iat * 1000))
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.tokens[jti] {
+		return true, nil
+	}
+	if revokedAt, ok := r.accounts[claimID(claims["account_id"])]; ok && revokedAt.After(issuedAt) {
+		return true, nil
+	}
+	if revokedAt, ok := r.sessions[claimID(claims["sid"])]; ok && revokedAt.After(issuedAt) {
+		return true, nil
+	}
+	if clientID, ok := claims["client_id"].(string); ok {
+		if revokedAt, ok := r.clients[clientID]; ok && revokedAt.After(issuedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *RevocationList) refreshIfStale() error {
+	r.mu.RLock()
+	fresh := time.Since(r.fetchedAt) < r.ttl
+	r.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	token, err := r.serviceTokens.Token()
+	if err != nil {
+		return err
+	}
+
+	var result []revocation
+	resp, err := r.client.R().
+		SetHeader("Authorization", "Bearer "+token).
+		SetResult(&result).
+		Get("/api/Authentication/Revocations")
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("failed to fetch token revocations: %s", resp.Status())
+	}
+
+	tokens := make(map[string]bool)
+	accounts := make(map[string]time.Time)
+	sessions := make(map[string]time.Time)
+	clients := make(map[string]time.Time)
+	for _, item := range result {
+		switch item.Kind {
+		case "jti":
+			tokens[item.Value] = true
+		case "account":
+			if item.RevokedAt.After(accounts[item.Value]) {
+				accounts[item.Value] = item.RevokedAt
+			}
+		case "session":
+			if item.RevokedAt.After(sessions[item.Value]) {
+				sessions[item.Value] = item.RevokedAt
+			}
+		case "client":
+			if item.RevokedAt.After(clients[item.Value]) {
+				clients[item.Value] = item.RevokedAt
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.tokens = tokens
+	r.accounts = accounts
+	r.sessions = sessions
+	r.clients = clients
+	r.fetchedAt = time.Now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+func claimID(value interface{}) string {
+	id, ok := value.(float64)
+	if !ok {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ServiceTokenSource obtains access tokens for this service from the account
+// service using the client_credentials grant and caches them until shortly
+// before they expire.
+type ServiceTokenSource struct {
+	client       *resty.Client
+	clientID     string
+	clientSecret string
+	scope        string
+	mu           sync.Mutex
+	token        string
+	expiresAt    time.Time
+}
+
+// NewServiceTokenSource returns nil when SERVICE_CLIENT_ID is not set.
+func NewServiceTokenSource(scopes ...string) *ServiceTokenSource {
+	clientID := os.Getenv("SERVICE_CLIENT_ID")
+	if clientID == "" {
+		return nil
+	}
+
+	baseURL := os.Getenv("ACCOUNT_SERVICE_URL")
+	if baseURL == "" {
+		panic("ACCOUNT_SERVICE_URL is not set")
+	}
+
+	client := resty.New()
+	client.SetHostURL(baseURL)
+
+	return &ServiceTokenSource{
+		client:       client,
+		clientID:     clientID,
+		clientSecret: os.Getenv("SERVICE_CLIENT_SECRET"),
+		scope:        strings.Join(scopes, " "),
+	}
+}
+
+func (s *ServiceTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	resp, err := s.client.R().
+		SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret)).
+		SetFormData(map[string]string{
+			"grant_type": "client_credentials",
+			"scope":      s.scope,
+		}).
+		SetResult(&result).
+		Post("/oauth/token")
+
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", fmt.Errorf("failed to obtain service token: %s", resp.Status())
+	}
+
+	s.token = result.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 30*time.Second)
+	return s.token, nil
+}
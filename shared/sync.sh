@@ -0,0 +1,41 @@
+#!/bin/sh
+# Copies the token verification code in shared/serviceauth into the utils
+# package of every service that validates account service tokens.
+#
+#   sh shared/sync.sh          rewrite the copies
+#   sh shared/sync.sh -check   fail if a copy differs from shared/serviceauth
+set -e
+
+root=$(cd "$(dirname "$0")/.." && pwd)
+services="hospital_service timetable_service document_service"
+
+check=false
+if [ "$1" = "-check" ]; then
+	check=true
+fi
+
+status=0
+for service in $services; do
+	for source in "$root"/shared/serviceauth/*.go; do
+		name=$(basename "$source")
+		target="$root/$service/utils/$name"
+		generated=$(mktemp)
+		{
+			echo "// Code generated by shared/sync.sh from shared/serviceauth/$name. DO NOT EDIT."
+			echo
+			cat "$source"
+		} >"$generated"
+
+		if $check; then
+			if ! cmp -s "$generated" "$target"; then
+				echo "$service/utils/$name is out of date; run sh shared/sync.sh" >&2
+				status=1
+			fi
+		else
+			cat "$generated" >"$target"
+		fi
+		rm "$generated"
+	done
+done
+
+exit $status
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"timetable_service/config"
+	"timetable_service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type doctorAvailability struct {
+	DoctorID     uint       `json:"doctorId"`
+	HospitalIDs  []uint     `json:"hospitalIds"`
+	NextFreeSlot *time.Time `json:"nextFreeSlot"`
+}
+
+// GetDoctorsAvailability answers, for a batch of doctors, at which hospitals
+// they work within the window and when their soonest free slot is. Doctors
+// without a matching timetable are left out of the response.
+func GetDoctorsAvailability(c *gin.Context) {
+	var input struct {
+		DoctorIDs  []uint     `json:"doctorIds" binding:"required,max=1000"`
+		HospitalID uint       `json:"hospitalId"`
+		From       *time.Time `json:"from"`
+		To         *time.Time `json:"to"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from := time.Now()
+	if input.From != nil && input.From.After(from) {
+		from = *input.From
+	}
+	if input.To != nil && !input.To.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'to' must be after 'from'"})
+		return
+	}
+
+	result := []doctorAvailability{}
+	if len(input.DoctorIDs) == 0 {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	query := config.DB.Preload("Appointments").
+		Where("doctor_id IN ?", input.DoctorIDs).
+		Where("\"to\" > ?", from).
+		Order("\"from\"")
+	if input.To != nil {
+		query = query.Where("\"from\" < ?", *input.To)
+	}
+	if input.HospitalID != 0 {
+		query = query.Where("hospital_id = ?", input.HospitalID)
+	}
+
+	var timetables []models.Timetable
+	if err := query.Find(&timetables).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve timetables"})
+		return
+	}
+
+	byDoctor := make(map[uint]*doctorAvailability)
+	for _, timetable := range timetables {
+		availability, ok := byDoctor[timetable.DoctorID]
+		if !ok {
+			availability = &doctorAvailability{DoctorID: timetable.DoctorID, HospitalIDs: []uint{}}
+			byDoctor[timetable.DoctorID] = availability
+		}
+
+		if !containsID(availability.HospitalIDs, timetable.HospitalID) {
+			availability.HospitalIDs = append(availability.HospitalIDs, timetable.HospitalID)
+		}
+
+		slot := firstFreeSlot(timetable, from, input.To)
+		if slot != nil && (availability.NextFreeSlot == nil || slot.Before(*availability.NextFreeSlot)) {
+			availability.NextFreeSlot = slot
+		}
+	}
+
+	for _, availability := range byDoctor {
+		result = append(result, *availability)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DoctorID < result[j].DoctorID })
+
+	c.JSON(http.StatusOK, result)
+}
+
+func firstFreeSlot(timetable models.Timetable, from time.Time, to *time.Time) *time.Time {
+	booked := make(map[int64]bool, len(timetable.Appointments))
+	for _, appointment := range timetable.Appointments {
+		booked[appointment.Time.Unix()] = true
+	}
+
+	for _, slot := range generateTimeSlots(timetable.From, timetable.To) {
+		if slot.Before(from) || booked[slot.Unix()] {
+			continue
+		}
+		if to != nil && !slot.Before(*to) {
+			return nil
+		}
+		return &slot
+	}
+	return nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
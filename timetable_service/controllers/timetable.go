@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"timetable_service/config"
@@ -9,6 +11,7 @@ import (
 	"timetable_service/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +32,10 @@ func CreateTimetable(c *gin.Context) {
 		return
 	}
 
+	if !requireHospitalPermission(c, input.HospitalID, "timetable.write") {
+		return
+	}
+
 	token := c.GetHeader("Authorization")
 	if token == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
@@ -207,6 +214,15 @@ func UpdateTimetable(c *gin.Context) {
 		return
 	}
 
+	if !requireHospitalPermission(c, timetable.HospitalID, "timetable.write") {
+		tx.Rollback()
+		return
+	}
+	if input.HospitalID != 0 && !requireHospitalPermission(c, input.HospitalID, "timetable.write") {
+		tx.Rollback()
+		return
+	}
+
 	if len(timetable.Appointments) > 0 {
 		tx.Rollback()
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot update timetable with existing appointments"})
@@ -357,6 +373,11 @@ func UpdateTimetable(c *gin.Context) {
 func DeleteTimetable(c *gin.Context) {
 	id := c.Param("id")
 
+	var timetable models.Timetable
+	if err := config.DB.First(&timetable, id).Error; err == nil && !requireHospitalPermission(c, timetable.HospitalID, "timetable.write") {
+		return
+	}
+
 	if err := config.DB.Delete(&models.Timetable{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete timetable"})
 		return
@@ -368,7 +389,12 @@ func DeleteTimetable(c *gin.Context) {
 func DeleteTimetableByDoctor(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := config.DB.Where("doctor_id = ?", id).Delete(&models.Timetable{}).Error; err != nil {
+	query := config.DB.Where("doctor_id = ?", id)
+	if all, hospitalIDs := utils.PermittedHospitals(c.MustGet("claims").(jwt.MapClaims), "timetable.write"); !all {
+		query = query.Where("hospital_id IN ?", hospitalIDs)
+	}
+
+	if err := query.Delete(&models.Timetable{}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete timetables"})
 		return
 	}
@@ -377,7 +403,15 @@ func DeleteTimetableByDoctor(c *gin.Context) {
 }
 
 func DeleteTimetableByHospital(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hospital ID"})
+		return
+	}
+
+	if !requireHospitalPermission(c, uint(id), "timetable.write") {
+		return
+	}
 
 	if err := config.DB.Where("hospital_id = ?", id).Delete(&models.Timetable{}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete timetables"})
@@ -448,8 +482,16 @@ func GetTimetableByDoctor(c *gin.Context) {
 }
 
 func GetTimetableByRoom(c *gin.Context) {
-	hospitalID := c.Param("id")
+	hospitalID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hospital ID"})
+		return
+	}
 	room := c.Param("room")
+
+	if !requireHospitalPermission(c, uint(hospitalID), "timetable.room.read") {
+		return
+	}
 	fromStr := c.Query("from")
 	toStr := c.Query("to")
 
@@ -515,7 +557,8 @@ func CreateAppointment(c *gin.Context) {
 	id := c.Param("id")
 
 	var input struct {
-		Time time.Time `json:"time" binding:"required"`
+		Time      time.Time `json:"time" binding:"required"`
+		PatientID uint      `json:"patientId"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -531,6 +574,15 @@ func CreateAppointment(c *gin.Context) {
 		return
 	}
 
+	patientID := userID
+	if input.PatientID != 0 && input.PatientID != userID {
+		if !utils.IsGuardianOf(c.MustGet("claims").(jwt.MapClaims), input.PatientID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a guardian of this patient"})
+			return
+		}
+		patientID = input.PatientID
+	}
+
 	var timetable models.Timetable
 	if err := config.DB.Preload("Appointments").First(&timetable, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Timetable not found"})
@@ -555,11 +607,18 @@ func CreateAppointment(c *gin.Context) {
 
 	appointment := models.Appointment{
 		TimetableID: timetable.ID,
-		UserID:      userID,
+		UserID:      patientID,
+		BookedByID:  userID,
 		Time:        input.Time,
 	}
 
-	if err := config.DB.Create(&appointment).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&appointment).Error; err != nil {
+			return err
+		}
+		return logAppointment(tx, appointment, userID, models.AppointmentBooked)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
 		return
 	}
@@ -583,29 +642,45 @@ func DeleteAppointment(c *gin.Context) {
 		return
 	}
 
-	roles, err := accountService.GetUserRoles(accessToken.(string))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles", "details": err.Error()})
-		return
-	}
-
-	isAdminOrManager := false
-	for _, role := range roles {
-		if role == "admin" || role == "manager" {
-			isAdminOrManager = true
-			break
+	claims := c.MustGet("claims").(jwt.MapClaims)
+	if appointment.UserID != userID && !utils.IsGuardianOf(claims, appointment.UserID) {
+		var timetable models.Timetable
+		if err := config.DB.First(&timetable, appointment.TimetableID).Error; err != nil || !utils.HasHospitalPermission(claims, timetable.HospitalID, "appointment.write") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to cancel this appointment"})
+			return
 		}
 	}
 
-	if appointment.UserID != userID && !isAdminOrManager {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to cancel this appointment"})
-		return
-	}
-
-	if err := config.DB.Delete(&appointment).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&appointment).Error; err != nil {
+			return err
+		}
+		return logAppointment(tx, appointment, userID, models.AppointmentCancelled)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete appointment"})
 		return
 	}
 
 	c.Status(http.StatusOK)
 }
+
+// requireHospitalPermission responds with 403 unless the access token grants
+// the permission in the hospital.
+func requireHospitalPermission(c *gin.Context, hospitalID uint, permission string) bool {
+	if !utils.HasHospitalPermission(c.MustGet("claims").(jwt.MapClaims), hospitalID, permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Permission %s required for hospital %d", permission, hospitalID)})
+		return false
+	}
+	return true
+}
+
+func logAppointment(tx *gorm.DB, appointment models.Appointment, actorID uint, action string) error {
+	return tx.Create(&models.AppointmentLog{
+		AppointmentID: appointment.ID,
+		PatientID:     appointment.UserID,
+		ActorID:       actorID,
+		Action:        action,
+		Time:          appointment.Time,
+	}).Error
+}
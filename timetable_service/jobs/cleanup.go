@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"timetable_service/config"
+	"timetable_service/models"
+	"timetable_service/utils"
+)
+
+// StartOrphanCleanup periodically deletes timetables whose doctor or hospital
+// no longer exists. It needs a service client (SERVICE_CLIENT_ID) because it
+// runs without an end user's token.
+func StartOrphanCleanup() {
+	tokens := utils.NewServiceTokenSource("accounts:read", "hospitals:read")
+	if tokens == nil {
+		log.Printf("SERVICE_CLIENT_ID is not set, orphaned timetable cleanup is disabled")
+		return
+	}
+
+	interval := time.Hour
+	if value := os.Getenv("TIMETABLE_CLEANUP_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			interval = parsed
+		}
+	}
+
+	doctorService := utils.NewDoctorService()
+	hospitalService := utils.NewHospitalService()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			cleanupOrphanedTimetables(tokens, doctorService, hospitalService)
+			<-ticker.C
+		}
+	}()
+}
+
+func cleanupOrphanedTimetables(tokens *utils.ServiceTokenSource, doctorService *utils.DoctorService, hospitalService *utils.HospitalService) {
+	token, err := tokens.Token()
+	if err != nil {
+		log.Printf("Failed to obtain service token for timetable cleanup: %v", err)
+		return
+	}
+
+	var doctorIDs []uint
+	if err := config.DB.Model(&models.Timetable{}).Distinct().Pluck("doctor_id", &doctorIDs).Error; err != nil {
+		log.Printf("Failed to list timetable doctors: %v", err)
+		return
+	}
+
+	for _, doctorID := range doctorIDs {
+		exists, err := doctorService.DoctorExists(doctorID, token)
+		if err != nil {
+			log.Printf("Failed to check doctor %d: %v", doctorID, err)
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		result := config.DB.Where("doctor_id = ?", doctorID).Delete(&models.Timetable{})
+		if result.Error != nil {
+			log.Printf("Failed to delete timetables of removed doctor %d: %v", doctorID, result.Error)
+			continue
+		}
+		log.Printf("Deleted %d timetables of removed doctor %d", result.RowsAffected, doctorID)
+	}
+
+	var hospitalIDs []uint
+	if err := config.DB.Model(&models.Timetable{}).Distinct().Pluck("hospital_id", &hospitalIDs).Error; err != nil {
+		log.Printf("Failed to list timetable hospitals: %v", err)
+		return
+	}
+
+	for _, hospitalID := range hospitalIDs {
+		exists, err := hospitalService.HospitalExists(hospitalID, token)
+		if err != nil {
+			log.Printf("Failed to check hospital %d: %v", hospitalID, err)
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		result := config.DB.Where("hospital_id = ?", hospitalID).Delete(&models.Timetable{})
+		if result.Error != nil {
+			log.Printf("Failed to delete timetables of removed hospital %d: %v", hospitalID, result.Error)
+			continue
+		}
+		log.Printf("Deleted %d timetables of removed hospital %d", result.RowsAffected, hospitalID)
+	}
+}
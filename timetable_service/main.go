@@ -2,6 +2,7 @@ package main
 
 import (
 	"timetable_service/config"
+	"timetable_service/jobs"
 	"timetable_service/models"
 	"timetable_service/routes"
 	"timetable_service/utils"
@@ -11,10 +12,12 @@ import (
 
 func main() {
     config.InitDB()
-    config.DB.AutoMigrate(&models.Timetable{}, &models.Appointment{})
+    config.DB.AutoMigrate(&models.Timetable{}, &models.Appointment{}, &models.AppointmentLog{})
 
     accountService := utils.NewAccountService()
 
+    jobs.StartOrphanCleanup()
+
     r := gin.Default()
 
     routes.InitTimetableRoutes(r, accountService)
@@ -1,12 +1,14 @@
 package middlewares
 
 import (
+	"log"
 	"net/http"
 	"strings"
 
 	"timetable_service/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 func AuthMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
@@ -25,79 +27,114 @@ func AuthMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
 
         tokenString := parts[1]
 
-        isValid, err := accountService.ValidateToken(tokenString)
+        claims, err := accountService.ParseToken(tokenString)
         if err != nil {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Error validating token", "details": err.Error()})
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token", "details": err.Error()})
             return
         }
-        if !isValid {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+
+        if _, ok := claims["account_id"].(float64); !ok {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "User token required"})
+            return
+        }
+
+        if !checkImpersonation(c, claims) {
             return
         }
 
         c.Set("accessToken", tokenString)
+        c.Set("claims", claims)
         c.Next()
     }
 }
 
-func AdminOrManagerMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
+// ServiceMiddleware accepts only service tokens issued by the account
+// service that carry the given scope.
+func ServiceMiddleware(accountService *utils.AccountService, scope string) gin.HandlerFunc {
     return func(c *gin.Context) {
-        accessToken, exists := c.Get("accessToken")
-        if !exists {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token not found"})
+        parts := strings.Fields(c.GetHeader("Authorization"))
+        if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be 'Bearer {token}'"})
             return
         }
 
-        roles, err := accountService.GetUserRoles(accessToken.(string))
+        claims, err := accountService.ParseToken(parts[1])
         if err != nil {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles", "details": err.Error()})
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token", "details": err.Error()})
             return
         }
 
-        hasPermission := false
-        for _, role := range roles {
-            if role == "admin" || role == "manager" {
-                hasPermission = true
-                break
-            }
-        }
-
-        if !hasPermission {
-            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin or Manager privileges required"})
+        clientID, _ := claims["client_id"].(string)
+        tokenScope, _ := claims["scope"].(string)
+        if _, isUser := claims["account_id"]; isUser || clientID == "" || !containsString(strings.Fields(tokenScope), scope) {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Service token with scope " + scope + " required"})
             return
         }
 
+        c.Set("claims", claims)
+        c.Set("client_id", clientID)
         c.Next()
     }
 }
 
-func AdminManagerOrDoctorMiddleware(accountService *utils.AccountService) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        accessToken, exists := c.Get("accessToken")
-        if !exists {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token not found"})
-            return
+func containsString(values []string, value string) bool {
+    for _, v := range values {
+        if v == value {
+            return true
         }
+    }
+    return false
+}
 
-        roles, err := accountService.GetUserRoles(accessToken.(string))
-        if err != nil {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Failed to get user roles", "details": err.Error()})
+// PermissionMiddleware requires the access token to carry the permission,
+// globally or in at least one hospital. Handlers check the hospital itself.
+func PermissionMiddleware(permission string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        claims, _ := c.Get("claims")
+        mapClaims, _ := claims.(jwt.MapClaims)
+
+        if all, hospitalIDs := utils.PermittedHospitals(mapClaims, permission); !all && len(hospitalIDs) == 0 {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission " + permission + " required"})
             return
         }
 
-        hasPermission := false
-        for _, role := range roles {
-            if role == "admin" || role == "manager" || role == "doctor" {
-                hasPermission = true
-                break
-            }
-        }
+        c.Next()
+    }
+}
+
+func VerifiedContactMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        claims, _ := c.Get("claims")
+        mapClaims, _ := claims.(jwt.MapClaims)
 
-        if !hasPermission {
-            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin, Manager or Doctor privileges required"})
+        if verified, _ := mapClaims["verified"].(bool); !verified {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Verify your email or phone number before booking appointments"})
             return
         }
 
         c.Next()
     }
 }
+
+// checkImpersonation logs requests made with an impersonation token, whose
+// act claim names the administrator, and rejects writes when it is read-only.
+func checkImpersonation(c *gin.Context, claims jwt.MapClaims) bool {
+    actor, ok := claims["act"].(map[string]interface{})
+    if !ok {
+        return true
+    }
+
+    log.Printf("Impersonated request %s %s: account %v acting as account %v",
+        c.Request.Method, c.Request.URL.Path, actor["account_id"], claims["account_id"])
+
+    readOnly, _ := claims["read_only"].(bool)
+    switch c.Request.Method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions:
+    default:
+        if readOnly {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Impersonation token is read-only"})
+            return false
+        }
+    }
+    return true
+}
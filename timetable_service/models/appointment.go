@@ -6,8 +6,26 @@ type Appointment struct {
     ID          uint      `gorm:"primaryKey" json:"id"`
     TimetableID uint      `json:"timetableId"`
     UserID      uint      `json:"userId"`
+    BookedByID  uint      `json:"bookedById"`
     Time        time.Time `json:"time"`
     CreatedAt   time.Time `json:"-"`
     UpdatedAt   time.Time `json:"-"`
     DeletedAt   *time.Time `gorm:"index" json:"-"`
 }
+
+const (
+    AppointmentBooked    = "booked"
+    AppointmentCancelled = "cancelled"
+)
+
+// AppointmentLog records who booked or cancelled an appointment. The actor
+// differs from the patient when a guardian acts on a dependent's behalf.
+type AppointmentLog struct {
+    ID            uint      `gorm:"primaryKey" json:"id"`
+    AppointmentID uint      `gorm:"index" json:"appointmentId"`
+    PatientID     uint      `gorm:"index" json:"patientId"`
+    ActorID       uint      `gorm:"index" json:"actorId"`
+    Action        string    `json:"action"`
+    Time          time.Time `json:"time"`
+    CreatedAt     time.Time `json:"createdAt"`
+}
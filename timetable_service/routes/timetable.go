@@ -11,18 +11,20 @@ import (
 func InitTimetableRoutes(r *gin.Engine, accountService *utils.AccountService) {
     timetableRoutes := r.Group("/api/Timetable")
     {
-        timetableRoutes.POST("/", middlewares.AuthMiddleware(accountService), middlewares.AdminOrManagerMiddleware(accountService), controllers.CreateTimetable)
-        timetableRoutes.PUT("/:id", middlewares.AuthMiddleware(accountService), middlewares.AdminOrManagerMiddleware(accountService), controllers.UpdateTimetable)
-        timetableRoutes.DELETE("/:id", middlewares.AuthMiddleware(accountService), middlewares.AdminOrManagerMiddleware(accountService), controllers.DeleteTimetable)
-        timetableRoutes.DELETE("/Doctor/:id", middlewares.AuthMiddleware(accountService), middlewares.AdminOrManagerMiddleware(accountService), controllers.DeleteTimetableByDoctor)
-        timetableRoutes.DELETE("/Hospital/:id", middlewares.AuthMiddleware(accountService), middlewares.AdminOrManagerMiddleware(accountService), controllers.DeleteTimetableByHospital)
+        timetableRoutes.POST("/", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("timetable.write"), controllers.CreateTimetable)
+        timetableRoutes.PUT("/:id", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("timetable.write"), controllers.UpdateTimetable)
+        timetableRoutes.DELETE("/:id", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("timetable.write"), controllers.DeleteTimetable)
+        timetableRoutes.DELETE("/Doctor/:id", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("timetable.write"), controllers.DeleteTimetableByDoctor)
+        timetableRoutes.DELETE("/Hospital/:id", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("timetable.write"), controllers.DeleteTimetableByHospital)
+
+        timetableRoutes.POST("/Availability", middlewares.ServiceMiddleware(accountService, "timetables:read"), controllers.GetDoctorsAvailability)
 
         timetableRoutes.GET("/Hospital/:id", middlewares.AuthMiddleware(accountService), controllers.GetTimetableByHospital)
         timetableRoutes.GET("/Doctor/:id", middlewares.AuthMiddleware(accountService), controllers.GetTimetableByDoctor)
-        timetableRoutes.GET("/Hospital/:id/Room/:room", middlewares.AuthMiddleware(accountService), middlewares.AdminManagerOrDoctorMiddleware(accountService), controllers.GetTimetableByRoom)
+        timetableRoutes.GET("/Hospital/:id/Room/:room", middlewares.AuthMiddleware(accountService), middlewares.PermissionMiddleware("timetable.room.read"), controllers.GetTimetableByRoom)
 
         timetableRoutes.GET("/:id/Appointments", middlewares.AuthMiddleware(accountService), controllers.GetAvailableAppointments)
-        timetableRoutes.POST("/:id/Appointments", middlewares.AuthMiddleware(accountService), controllers.CreateAppointment)
+        timetableRoutes.POST("/:id/Appointments", middlewares.AuthMiddleware(accountService), middlewares.VerifiedContactMiddleware(), controllers.CreateAppointment)
     }
 
     appointmentRoutes := r.Group("/api/Appointment")
@@ -1,3 +1,5 @@
+//go:generate sh ../../shared/sync.sh
+
 package utils
 
 import (
@@ -6,6 +8,7 @@ import (
 	"os"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 type HospitalService struct {
@@ -14,8 +17,10 @@ type HospitalService struct {
 }
 
 type AccountService struct {
-	client  *resty.Client
-	baseURL string
+	client      *resty.Client
+	baseURL     string
+	keys        *KeySet
+	revocations *RevocationList
 }
 
 func NewHospitalService() *HospitalService {
@@ -57,6 +62,27 @@ func (h *HospitalService) ValidateHospitalAndRoom(hospitalID uint, roomName stri
 	return false, fmt.Errorf("room '%s' not found in hospital '%s'", roomName, hospital.Name)
 }
 
+// HospitalExists reports whether the hospital service still knows the
+// hospital. A 404 is the only response treated as "does not exist".
+func (h *HospitalService) HospitalExists(hospitalID uint, token string) (bool, error) {
+	resp, err := h.client.R().
+		SetHeader("Authorization", "Bearer "+token).
+		Get(fmt.Sprintf("/api/Hospitals/%d", hospitalID))
+
+	if err != nil {
+		return false, err
+	}
+
+	switch resp.StatusCode() {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to get hospital data: %s", resp.Status())
+	}
+}
+
 type Hospital struct {
 	ID      uint   `json:"id"`
 	Name    string `json:"name"`
@@ -79,58 +105,33 @@ func NewAccountService() *AccountService {
 	client.SetHostURL(baseURL)
 
 	return &AccountService{
-		client:  client,
-		baseURL: baseURL,
+		client:      client,
+		baseURL:     baseURL,
+		keys:        NewKeySet(client),
+		revocations: NewRevocationList(client),
 	}
 }
 
-func (a *AccountService) ValidateToken(token string) (bool, error) {
-	var result struct {
-		IsValid bool `json:"isValid"`
-	}
-
-	resp, err := a.client.R().
-		SetQueryParam("accessToken", token).
-		SetResult(&result).
-		Get("/api/Authentication/Validate")
-
+func (a *AccountService) ParseToken(token string) (jwt.MapClaims, error) {
+	parsed, err := jwt.Parse(token, a.keys.Keyfunc)
 	if err != nil {
-		return false, err
-	}
-
-	if resp.StatusCode() != 200 {
-		return false, fmt.Errorf("failed to validate token: %s", resp.Status())
+		return nil, err
 	}
 
-	return result.IsValid, nil
-}
-
-func (a *AccountService) GetUserRoles(token string) ([]string, error) {
-	var result struct {
-		Roles []struct {
-			Name string `json:"name"`
-		} `json:"roles"`
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return nil, fmt.Errorf("invalid token claims")
 	}
 
-	resp, err := a.client.R().
-		SetHeader("Authorization", "Bearer "+token).
-		SetResult(&result).
-		Get("/api/Accounts/Me")
-
+	revoked, err := a.revocations.IsRevoked(claims)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("failed to get user roles, status: %s", resp.Status())
-	}
-
-	var roles []string
-	for _, role := range result.Roles {
-		roles = append(roles, role.Name)
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
 	}
 
-	return roles, nil
+	return claims, nil
 }
 
 func (a *AccountService) GetUserID(token string) (uint, error) {
@@ -193,3 +194,24 @@ func (d *DoctorService) ValidateDoctor(doctorID uint, token string) (bool, error
 
 	return result.ID == doctorID, nil
 }
+
+// DoctorExists reports whether the account service still knows the doctor.
+// A 404 is the only response treated as "does not exist".
+func (d *DoctorService) DoctorExists(doctorID uint, token string) (bool, error) {
+	resp, err := d.client.R().
+		SetHeader("Authorization", "Bearer "+token).
+		Get(fmt.Sprintf("/api/Doctors/%d", doctorID))
+
+	if err != nil {
+		return false, err
+	}
+
+	switch resp.StatusCode() {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to validate doctor: %s", resp.Status())
+	}
+}